@@ -0,0 +1,121 @@
+// Package journal persists the set of pactl module ids each deployed
+// profile has created, so a daemon that's SIGKILLed, crashes, or survives
+// a reboot doesn't leak modules - and the duplicate sinks that come with
+// them - on its next run.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Entry records the modules a single deployed profile created.
+type Entry struct {
+	Profile   string `json:"profile"`
+	ModuleIDs []int  `json:"module_ids"`
+}
+
+// Journal is the on-disk record of every profile currently deployed.
+type Journal struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Path returns the journal file's location, honoring $XDG_STATE_HOME and
+// falling back to ~/.local/state per the XDG base directory spec.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "pubtalk", "deployed.json"), nil
+}
+
+// Load reads the journal from disk. A missing file is treated as an empty
+// journal, since that's the normal state after a clean shutdown.
+func Load() (*Journal, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Journal{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal '%s': %w", path, err)
+	}
+
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal '%s': %w", path, err)
+	}
+	return &j, nil
+}
+
+// Save atomically writes the journal to disk: it writes to a temp file in
+// the same directory and renames it over the real path, so a crash
+// mid-write can never leave a truncated or half-written journal behind.
+func (j *Journal) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create journal directory '%s': %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".deployed-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp journal file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp journal file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp journal file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp journal file into place: %w", err)
+	}
+	return nil
+}
+
+// Set records (or replaces) the modules loaded for profile.
+func (j *Journal) Set(profile string, moduleIDs []int) {
+	for i, e := range j.Entries {
+		if e.Profile == profile {
+			j.Entries[i].ModuleIDs = moduleIDs
+			return
+		}
+	}
+	j.Entries = append(j.Entries, Entry{Profile: profile, ModuleIDs: moduleIDs})
+}
+
+// Remove deletes profile's entry. Callers should only do this once every id
+// it listed has actually been confirmed unloaded.
+func (j *Journal) Remove(profile string) {
+	for i, e := range j.Entries {
+		if e.Profile == profile {
+			j.Entries = append(j.Entries[:i], j.Entries[i+1:]...)
+			return
+		}
+	}
+}
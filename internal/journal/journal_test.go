@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func withStateDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	return dir
+}
+
+func TestLoadMissingJournalReturnsEmpty(t *testing.T) {
+	withStateDir(t)
+
+	j, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(j.Entries) != 0 {
+		t.Fatalf("expected empty journal, got %+v", j.Entries)
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	dir := withStateDir(t)
+
+	j := &Journal{}
+	j.Set("meeting", []int{1, 2, 3})
+	if err := j.Save(); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	path := filepath.Join(dir, "pubtalk", "deployed.json")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected journal file at %s: %v", path, err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	want := []Entry{{Profile: "meeting", ModuleIDs: []int{1, 2, 3}}}
+	if !reflect.DeepEqual(loaded.Entries, want) {
+		t.Fatalf("expected %+v, got %+v", want, loaded.Entries)
+	}
+}
+
+func TestSetReplacesExistingEntry(t *testing.T) {
+	withStateDir(t)
+
+	j := &Journal{}
+	j.Set("meeting", []int{1})
+	j.Set("meeting", []int{2, 3})
+
+	if len(j.Entries) != 1 {
+		t.Fatalf("expected a single entry, got %d", len(j.Entries))
+	}
+	if !reflect.DeepEqual(j.Entries[0].ModuleIDs, []int{2, 3}) {
+		t.Fatalf("expected replaced module ids [2 3], got %v", j.Entries[0].ModuleIDs)
+	}
+}
+
+func TestRemoveDeletesEntry(t *testing.T) {
+	withStateDir(t)
+
+	j := &Journal{}
+	j.Set("meeting", []int{1})
+	j.Set("standup", []int{2})
+	j.Remove("meeting")
+
+	if len(j.Entries) != 1 || j.Entries[0].Profile != "standup" {
+		t.Fatalf("expected only 'standup' to remain, got %+v", j.Entries)
+	}
+}
+
+func TestSaveIsAtomic(t *testing.T) {
+	dir := withStateDir(t)
+
+	j := &Journal{}
+	j.Set("meeting", []int{1})
+	if err := j.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "pubtalk", ".deployed-*.json.tmp"))
+	if err != nil {
+		t.Fatalf("unexpected error globbing: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected no leftover temp files, found %v", matches)
+	}
+}
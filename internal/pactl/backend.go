@@ -0,0 +1,65 @@
+package pactl
+
+// Module describes a single loaded PulseAudio/PipeWire module.
+type Module struct {
+	ID       int
+	Name     string
+	Argument string
+	// NUsed is the server's "in use" counter for the module (e.g. the number
+	// of streams currently routed through a sink it owns), or -1 if the
+	// server did not report one.
+	NUsed int
+}
+
+// Sink describes a playback sink.
+type Sink struct {
+	Index         int
+	Name          string
+	MonitorSource string
+}
+
+// EventFacility identifies the kind of object an Event refers to.
+type EventFacility int
+
+const (
+	FacilitySink EventFacility = iota
+	FacilitySource
+	FacilitySinkInput
+	FacilitySourceOutput
+	FacilityModule
+	FacilityClient
+	FacilityServer
+)
+
+// EventType identifies what happened to the object named in an Event.
+type EventType int
+
+const (
+	EventNew EventType = iota
+	EventChange
+	EventRemove
+)
+
+// Event is a single subscription notification from the server.
+type Event struct {
+	Facility EventFacility
+	Type     EventType
+	Index    uint32
+}
+
+// Backend abstracts the server operations profiles.Manager needs. PactlBackend
+// implements it by shelling out to pactl; native.Backend (in the native
+// subpackage) implements it by speaking the PulseAudio native protocol
+// directly over the control socket. Both plug into Manager identically.
+type Backend interface {
+	ServerInfo() (ServerInfo, error)
+	ListSinks() ([]Sink, error)
+	ListModules() ([]Module, error)
+	ListSinkInputs() ([]SinkInput, error)
+	LoadModule(name string, args ...string) (int, error)
+	UnloadModule(id int) error
+	MoveSinkInput(id int, sink string) error
+	// Subscribe starts listening for server events and returns a channel
+	// that receives them. The channel is closed when the subscription ends.
+	Subscribe() (<-chan Event, error)
+}
@@ -0,0 +1,687 @@
+// Package native speaks a minimal subset of the PulseAudio native protocol
+// directly over the control socket, instead of shelling out to pactl. It
+// backs pactl.Backend with atomic, typed introspection (no line-prefix
+// parsing) and a real event stream for reactive routing.
+//
+// Only the commands pubtalk needs are implemented: authentication, module
+// and sink introspection, load/unload, sink-input moves and subscriptions.
+// See pulse/internal.h in the PulseAudio source for the full protocol.
+package native
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gemini-audio/internal/pactl"
+)
+
+// protocolVersion is the native protocol version we claim during AUTH. Good
+// enough to get typed module/sink info and subscriptions from any server
+// since PulseAudio 1.0.
+const protocolVersion = 32
+
+// Command codes, in the order PulseAudio defines them in pulse/internal.h.
+// PA_COMMAND_SET_*_VOLUME and PA_COMMAND_SET_*_MUTE are each distinct
+// commands per target (sink/sink-input/source); they're kept as separate
+// entries here, even though pubtalk doesn't call most of them, so the
+// trailing iotas - commandLoadModule and commandUnloadModule above all -
+// land on their real opcodes instead of drifting off by however many of
+// these got collapsed together.
+//
+// PA_COMMAND_SUBSCRIBE_EVENT is deliberately NOT in this block: it's a
+// server->client notification grouped much later in the real enum (with
+// REQUEST/OVERFLOW/UNDERFLOW/*_STREAM_KILLED), not adjacent to
+// PA_COMMAND_SUBSCRIBE. Giving it an iota here would shift every
+// client->server command below it off its real opcode; it's defined
+// separately below instead, the same way commandMoveSinkInput is.
+const (
+	commandError = iota
+	commandTimeout
+	commandReply
+	commandCreatePlaybackStream
+	commandDeletePlaybackStream
+	commandCreateRecordStream
+	commandDeleteRecordStream
+	commandExit
+	commandAuth
+	commandSetClientName
+	commandLookupSink
+	commandLookupSource
+	commandDrainPlaybackStream
+	commandStat
+	commandGetPlaybackLatency
+	commandCreateUploadStream
+	commandDeleteUploadStream
+	commandFinishUploadStream
+	commandPlaySample
+	commandRemoveSample
+	commandGetServerInfo
+	commandGetSinkInfo
+	commandGetSinkInfoList
+	commandGetSourceInfo
+	commandGetSourceInfoList
+	commandGetModuleInfo
+	commandGetModuleInfoList
+	commandGetClientInfo
+	commandGetClientInfoList
+	commandGetSinkInputInfo
+	commandGetSinkInputInfoList
+	commandGetSourceOutputInfo
+	commandGetSourceOutputInfoList
+	commandGetSampleInfo
+	commandGetSampleInfoList
+	commandSubscribe
+	commandSetSinkVolume
+	commandSetSinkInputVolume
+	commandSetSourceVolume
+	commandSetSinkMute
+	commandSetSourceMute
+	commandCork
+	commandFlush
+	commandTrigger
+	commandSetDefaultSink
+	commandSetDefaultSource
+	commandSetPlaybackStreamName
+	commandSetRecordStreamName
+	commandKillClient
+	commandKillSinkInput
+	commandKillSourceOutput
+	commandLoadModule
+	commandUnloadModule
+)
+
+// commandSubscribeEvent is PA_COMMAND_SUBSCRIBE_EVENT. See the comment on
+// the const block above for why it isn't one of those iotas.
+const commandSubscribeEvent = 66
+
+// commandMoveSinkInput is PA_COMMAND_MOVE_SINK_INPUT, further down the enum
+// past commandSubscribeEvent and the rest of that notification group.
+const commandMoveSinkInput = 67
+
+// Backend implements pactl.Backend over the native protocol.
+type Backend struct {
+	conn net.Conn
+	seq  uint32
+
+	mu      sync.Mutex
+	pending map[uint32]chan reply
+
+	events chan pactl.Event
+}
+
+// reply is what the read loop hands back to a waiting call(): either the
+// reply payload, or the server's error code for a PA_COMMAND_ERROR.
+type reply struct {
+	tr    *tagReader
+	code  uint32
+	isErr bool
+}
+
+// Dial connects to the PulseAudio native socket (defaulting to
+// $XDG_RUNTIME_DIR/pulse/native), authenticates with the local cookie and
+// returns a ready-to-use Backend.
+func Dial() (*Backend, error) {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return nil, fmt.Errorf("native: XDG_RUNTIME_DIR is not set")
+	}
+	return DialSocket(filepath.Join(runtimeDir, "pulse", "native"))
+}
+
+// DialSocket connects to an explicit native protocol socket path.
+func DialSocket(path string) (*Backend, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("native: failed to connect to %s: %w", path, err)
+	}
+
+	b := &Backend{
+		conn:    conn,
+		pending: make(map[uint32]chan reply),
+		events:  make(chan pactl.Event, 32),
+	}
+	go b.readLoop()
+
+	if err := b.authenticate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := b.call(commandSetClientName, func(w *tagWriter) {
+		// Empty proplist (just the tag and the NUL-key terminator): keep the
+		// handshake minimal.
+		w.buf = append(w.buf, tagProplist)
+		w.PutString("")
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("native: set client name failed: %w", err)
+	}
+	return b, nil
+}
+
+func (b *Backend) authenticate() error {
+	cookie, err := readCookie()
+	if err != nil {
+		return fmt.Errorf("native: failed to read auth cookie: %w", err)
+	}
+	_, err = b.call(commandAuth, func(w *tagWriter) {
+		w.PutU32(protocolVersion)
+		w.PutArbitrary(cookie)
+	})
+	if err != nil {
+		return fmt.Errorf("native: auth failed: %w", err)
+	}
+	return nil
+}
+
+func readCookie() ([]byte, error) {
+	path := os.Getenv("PULSE_COOKIE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".config", "pulse", "cookie")
+	}
+	return os.ReadFile(path)
+}
+
+// Close closes the underlying connection and stops the read loop.
+func (b *Backend) Close() error {
+	return b.conn.Close()
+}
+
+// call sends a command with the tag-encoded arguments built by build, waits
+// for the matching reply and returns its payload reader.
+func (b *Backend) call(cmd uint32, build func(w *tagWriter)) (*tagReader, error) {
+	seq := atomic.AddUint32(&b.seq, 1) - 1
+
+	w := &tagWriter{}
+	w.PutU32(cmd)
+	w.PutU32(seq)
+	if build != nil {
+		build(w)
+	}
+
+	replyCh := make(chan reply, 1)
+	b.mu.Lock()
+	b.pending[seq] = replyCh
+	b.mu.Unlock()
+
+	if err := writePacket(b.conn, w.Bytes()); err != nil {
+		b.mu.Lock()
+		delete(b.pending, seq)
+		b.mu.Unlock()
+		return nil, err
+	}
+
+	r, ok := <-replyCh
+	if !ok {
+		return nil, fmt.Errorf("native: connection closed while waiting for reply")
+	}
+	if r.isErr {
+		return nil, fmt.Errorf("native: server returned error code %d", r.code)
+	}
+	return r.tr, nil
+}
+
+// readLoop demultiplexes incoming packets: replies/errors are routed to the
+// matching pending call, unsolicited subscribe events go to the events
+// channel. It runs for the lifetime of the connection.
+func (b *Backend) readLoop() {
+	defer close(b.events)
+	defer b.failPending()
+
+	r := bufio.NewReader(b.conn)
+	for {
+		payload, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		tr := newTagReader(payload)
+		cmd, err := tr.GetU32()
+		if err != nil {
+			continue
+		}
+
+		if cmd == commandSubscribeEvent {
+			b.dispatchEvent(tr)
+			continue
+		}
+
+		seq, err := tr.GetU32()
+		if err != nil {
+			continue
+		}
+		if cmd == commandError {
+			code, _ := tr.GetU32()
+			b.replyTo(seq, reply{isErr: true, code: code})
+			continue
+		}
+		b.replyTo(seq, reply{tr: tr})
+	}
+}
+
+func (b *Backend) replyTo(seq uint32, r reply) {
+	b.mu.Lock()
+	ch, ok := b.pending[seq]
+	if ok {
+		delete(b.pending, seq)
+	}
+	b.mu.Unlock()
+	if ok {
+		ch <- r
+	}
+}
+
+func (b *Backend) failPending() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for seq, ch := range b.pending {
+		close(ch)
+		delete(b.pending, seq)
+	}
+}
+
+func (b *Backend) dispatchEvent(tr *tagReader) {
+	code, err := tr.GetU32()
+	if err != nil {
+		return
+	}
+	idx, err := tr.GetU32()
+	if err != nil {
+		return
+	}
+	ev := pactl.Event{
+		Facility: pactl.EventFacility(code & 0xF),
+		Type:     pactl.EventType((code >> 4) & 0xF),
+		Index:    idx,
+	}
+	select {
+	case b.events <- ev:
+	default:
+		// Drop the event rather than block the read loop; subscribers that
+		// care about every event should drain the channel promptly.
+	}
+}
+
+// Subscribe asks the server for sink/sink-input/module events and returns
+// the channel they'll arrive on.
+func (b *Backend) Subscribe() (<-chan pactl.Event, error) {
+	const maskSink = 0x1
+	const maskSinkInput = 0x4
+	const maskModule = 0x10
+	if _, err := b.call(commandSubscribe, func(w *tagWriter) {
+		w.PutU32(maskSink | maskSinkInput | maskModule)
+	}); err != nil {
+		return nil, fmt.Errorf("native: subscribe failed: %w", err)
+	}
+	return b.events, nil
+}
+
+// ServerInfo fetches the server's identity, used to pick a module topology.
+func (b *Backend) ServerInfo() (pactl.ServerInfo, error) {
+	tr, err := b.call(commandGetServerInfo, nil)
+	if err != nil {
+		return pactl.ServerInfo{}, fmt.Errorf("native: get server info failed: %w", err)
+	}
+
+	if _, err := tr.GetString(); err != nil { // user_name
+		return pactl.ServerInfo{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // host_name
+		return pactl.ServerInfo{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // server_version
+		return pactl.ServerInfo{}, err
+	}
+	name, err := tr.GetString() // server_name
+	if err != nil {
+		return pactl.ServerInfo{}, err
+	}
+
+	info := pactl.ServerInfo{ServerName: name, Type: pactl.ServerPulseAudio}
+	if strings.Contains(name, "PipeWire") {
+		info.Type = pactl.ServerPipeWire
+	}
+	return info, nil
+}
+
+// ListModules fetches structured module info, including the server's n_used
+// counter for each module.
+func (b *Backend) ListModules() ([]pactl.Module, error) {
+	tr, err := b.call(commandGetModuleInfoList, nil)
+	if err != nil {
+		return nil, fmt.Errorf("native: get module info list failed: %w", err)
+	}
+
+	var modules []pactl.Module
+	for tr.Remaining() {
+		idx, err := tr.GetU32()
+		if err != nil {
+			return modules, err
+		}
+		name, err := tr.GetString()
+		if err != nil {
+			return modules, err
+		}
+		arg, err := tr.GetString()
+		if err != nil {
+			return modules, err
+		}
+		nUsed, err := tr.GetU32()
+		if err != nil {
+			return modules, err
+		}
+		modules = append(modules, pactl.Module{
+			ID:       int(idx),
+			Name:     name,
+			Argument: arg,
+			NUsed:    int(int32(nUsed)), // the server sends -1 (as uint32) when unknown
+		})
+	}
+	return modules, nil
+}
+
+// ListSinks fetches structured sink info.
+func (b *Backend) ListSinks() ([]pactl.Sink, error) {
+	tr, err := b.call(commandGetSinkInfoList, nil)
+	if err != nil {
+		return nil, fmt.Errorf("native: get sink info list failed: %w", err)
+	}
+
+	var sinks []pactl.Sink
+	for tr.Remaining() {
+		sink, err := readSinkInfo(tr)
+		if err != nil {
+			return sinks, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// readSinkInfo decodes one GetSinkInfoList record. Its wire layout carries
+// many more fields than pubtalk needs (description, sample spec, volume,
+// ports, ...); they're all walked in order and discarded so the reader
+// stays aligned for the next record in the same reply. Mirrors
+// sink_fill_tagstruct in PulseAudio's protocol-native.c for the protocol
+// version negotiated in Dial (21+: ports and formats are both present).
+func readSinkInfo(tr *tagReader) (pactl.Sink, error) {
+	idx, err := tr.GetU32()
+	if err != nil {
+		return pactl.Sink{}, err
+	}
+	name, err := tr.GetString()
+	if err != nil {
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // description
+		return pactl.Sink{}, err
+	}
+	if err := tr.SkipSampleSpec(); err != nil {
+		return pactl.Sink{}, err
+	}
+	if err := tr.SkipChannelMap(); err != nil {
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // owning module index
+		return pactl.Sink{}, err
+	}
+	if err := tr.SkipCVolume(); err != nil {
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetBoolean(); err != nil { // muted
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // monitor source index
+		return pactl.Sink{}, err
+	}
+	monitor, err := tr.GetString() // monitor source name
+	if err != nil {
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetUsec(); err != nil { // latency
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // driver
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // flags
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetProplist(); err != nil {
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetUsec(); err != nil { // requested latency
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // base volume
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // state
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // n_volume_steps
+		return pactl.Sink{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // owning card index
+		return pactl.Sink{}, err
+	}
+	nPorts, err := tr.GetU32()
+	if err != nil {
+		return pactl.Sink{}, err
+	}
+	for i := uint32(0); i < nPorts; i++ {
+		if _, err := tr.GetString(); err != nil { // port name
+			return pactl.Sink{}, err
+		}
+		if _, err := tr.GetString(); err != nil { // port description
+			return pactl.Sink{}, err
+		}
+		if _, err := tr.GetU32(); err != nil { // port priority
+			return pactl.Sink{}, err
+		}
+		if _, err := tr.GetU32(); err != nil { // port availability
+			return pactl.Sink{}, err
+		}
+	}
+	if _, err := tr.GetString(); err != nil { // active port name
+		return pactl.Sink{}, err
+	}
+	nFormats, err := tr.GetU8()
+	if err != nil {
+		return pactl.Sink{}, err
+	}
+	for i := uint8(0); i < nFormats; i++ {
+		if err := tr.SkipFormatInfo(); err != nil {
+			return pactl.Sink{}, err
+		}
+	}
+
+	return pactl.Sink{Index: int(idx), Name: name, MonitorSource: monitor}, nil
+}
+
+// ListSinkInputs fetches structured sink-input info.
+func (b *Backend) ListSinkInputs() ([]pactl.SinkInput, error) {
+	tr, err := b.call(commandGetSinkInputInfoList, nil)
+	if err != nil {
+		return nil, fmt.Errorf("native: get sink input info list failed: %w", err)
+	}
+
+	var inputs []pactl.SinkInput
+	for tr.Remaining() {
+		input, err := readSinkInputInfo(tr)
+		if err != nil {
+			return inputs, err
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// readSinkInputInfo decodes one GetSinkInputInfoList record, mirroring
+// sink_input_fill_tagstruct in protocol-native.c for protocol version 21+.
+// ApplicationName and ProcessBinary aren't scalar fields on the record at
+// all - the native protocol only carries them as proplist entries, so
+// they're pulled out of the decoded proplist once the fixed fields are out
+// of the way.
+func readSinkInputInfo(tr *tagReader) (pactl.SinkInput, error) {
+	idx, err := tr.GetU32()
+	if err != nil {
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // name
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // owning module index
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // client index
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetU32(); err != nil { // sink index
+		return pactl.SinkInput{}, err
+	}
+	if err := tr.SkipSampleSpec(); err != nil {
+		return pactl.SinkInput{}, err
+	}
+	if err := tr.SkipChannelMap(); err != nil {
+		return pactl.SinkInput{}, err
+	}
+	if err := tr.SkipCVolume(); err != nil {
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetUsec(); err != nil { // buffer latency
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetUsec(); err != nil { // sink latency
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // resample method
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetString(); err != nil { // driver
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetBoolean(); err != nil { // muted
+		return pactl.SinkInput{}, err
+	}
+	props, err := tr.GetProplist()
+	if err != nil {
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetBoolean(); err != nil { // corked
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetBoolean(); err != nil { // has_volume
+		return pactl.SinkInput{}, err
+	}
+	if _, err := tr.GetBoolean(); err != nil { // volume_writable
+		return pactl.SinkInput{}, err
+	}
+	if err := tr.SkipFormatInfo(); err != nil {
+		return pactl.SinkInput{}, err
+	}
+
+	return pactl.SinkInput{
+		ID:              int(idx),
+		ApplicationName: props["application.name"],
+		ProcessBinary:   props["application.process.binary"],
+	}, nil
+}
+
+// LoadModule loads a module and returns its new id.
+func (b *Backend) LoadModule(name string, args ...string) (int, error) {
+	argline := ""
+	for i, a := range args {
+		if i > 0 {
+			argline += " "
+		}
+		argline += a
+	}
+	tr, err := b.call(commandLoadModule, func(w *tagWriter) {
+		w.PutString(name)
+		w.PutString(argline)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("native: load module '%s' failed: %w", name, err)
+	}
+	id, err := tr.GetU32()
+	if err != nil {
+		return 0, fmt.Errorf("native: load module '%s': malformed reply: %w", name, err)
+	}
+	return int(id), nil
+}
+
+// UnloadModule unloads a module by id.
+func (b *Backend) UnloadModule(id int) error {
+	_, err := b.call(commandUnloadModule, func(w *tagWriter) {
+		w.PutU32(uint32(id))
+	})
+	if err != nil {
+		return fmt.Errorf("native: unload module %d failed: %w", id, err)
+	}
+	return nil
+}
+
+// MoveSinkInput moves a sink input to a different sink by name.
+func (b *Backend) MoveSinkInput(id int, sink string) error {
+	_, err := b.call(commandMoveSinkInput, func(w *tagWriter) {
+		w.PutU32(uint32(id))
+		w.PutString(sink)
+	})
+	if err != nil {
+		return fmt.Errorf("native: move sink-input %d to '%s' failed: %w", id, sink, err)
+	}
+	return nil
+}
+
+// writePacket frames a command payload with the pstream descriptor and
+// writes it to conn. Memblock fields are zeroed; this backend never
+// transfers sample data.
+func writePacket(conn net.Conn, payload []byte) error {
+	var desc [20]byte
+	binary.BigEndian.PutUint32(desc[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(desc[4:8], 0xFFFFFFFF) // channel: PA_INVALID_INDEX for control packets
+	if _, err := conn.Write(desc[:]); err != nil {
+		return fmt.Errorf("native: failed to write packet descriptor: %w", err)
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return fmt.Errorf("native: failed to write packet payload: %w", err)
+	}
+	return nil
+}
+
+func readPacket(r *bufio.Reader) ([]byte, error) {
+	var desc [20]byte
+	if _, err := readFull(r, desc[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(desc[0:4])
+	payload := make([]byte, length)
+	if _, err := readFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func readFull(r *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+var _ pactl.Backend = (*Backend)(nil)
@@ -0,0 +1,121 @@
+package native
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBackendSendsRealProtocolOpcodes dials a real unix socket against a
+// scripted fake server that speaks just enough of the wire framing (the
+// 20-byte pstream descriptor plus a [cmd, seq, ...] tagstruct payload) to
+// answer AUTH, SET_CLIENT_NAME, LOAD_MODULE, UNLOAD_MODULE and
+// MOVE_SINK_INPUT. Unlike TestCommandCodesMatchPulseAudioProtocol (which
+// only pins the constants), this exercises the actual bytes DialSocket and
+// the backend methods put on a socket, since there's no real
+// PulseAudio/PipeWire server to dial in CI.
+func TestBackendSendsRealProtocolOpcodes(t *testing.T) {
+	dir := t.TempDir()
+	sockPath := filepath.Join(dir, "native")
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("failed to listen on fake socket: %v", err)
+	}
+	defer ln.Close()
+
+	cookiePath := filepath.Join(dir, "cookie")
+	if err := os.WriteFile(cookiePath, make([]byte, 256), 0o600); err != nil {
+		t.Fatalf("failed to write fake cookie: %v", err)
+	}
+	t.Setenv("PULSE_COOKIE", cookiePath)
+
+	gotCmds := make(chan uint32, 8)
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- runFakeServer(ln, gotCmds) }()
+
+	b, err := DialSocket(sockPath)
+	if err != nil {
+		t.Fatalf("DialSocket: %v", err)
+	}
+	defer b.Close()
+
+	if cmd := <-gotCmds; cmd != commandAuth {
+		t.Fatalf("expected AUTH (%d) on the wire, got %d", commandAuth, cmd)
+	}
+	if cmd := <-gotCmds; cmd != commandSetClientName {
+		t.Fatalf("expected SET_CLIENT_NAME (%d) on the wire, got %d", commandSetClientName, cmd)
+	}
+
+	id, err := b.LoadModule("module-null-sink", "sink_name=x")
+	if err != nil {
+		t.Fatalf("LoadModule: %v", err)
+	}
+	if id != 42 {
+		t.Fatalf("LoadModule: got id %d, want 42", id)
+	}
+	if cmd := <-gotCmds; cmd != commandLoadModule {
+		t.Fatalf("LoadModule put command %d on the wire, want %d", cmd, commandLoadModule)
+	}
+
+	if err := b.UnloadModule(42); err != nil {
+		t.Fatalf("UnloadModule: %v", err)
+	}
+	if cmd := <-gotCmds; cmd != commandUnloadModule {
+		t.Fatalf("UnloadModule put command %d on the wire, want %d", cmd, commandUnloadModule)
+	}
+
+	if err := b.MoveSinkInput(7, "other-sink"); err != nil {
+		t.Fatalf("MoveSinkInput: %v", err)
+	}
+	if cmd := <-gotCmds; cmd != commandMoveSinkInput {
+		t.Fatalf("MoveSinkInput put command %d on the wire, want %d", cmd, commandMoveSinkInput)
+	}
+
+	if err := <-serverErr; err != nil {
+		t.Fatalf("fake server: %v", err)
+	}
+}
+
+// runFakeServer accepts a single connection and answers each request in
+// turn with a bare PA_COMMAND_REPLY (plus a module id for LOAD_MODULE),
+// forwarding the command code it saw on cmds. It reuses the package's own
+// packet framing (readPacket/writePacket) so it's exercising the same wire
+// format the real backend speaks.
+func runFakeServer(ln net.Listener, cmds chan<- uint32) error {
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for i := 0; i < 5; i++ {
+		payload, err := readPacket(r)
+		if err != nil {
+			return err
+		}
+		tr := newTagReader(payload)
+		cmd, err := tr.GetU32()
+		if err != nil {
+			return err
+		}
+		seq, err := tr.GetU32()
+		if err != nil {
+			return err
+		}
+		cmds <- cmd
+
+		w := &tagWriter{}
+		w.PutU32(commandReply)
+		w.PutU32(seq)
+		if cmd == commandLoadModule {
+			w.PutU32(42)
+		}
+		if err := writePacket(conn, w.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
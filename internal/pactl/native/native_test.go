@@ -0,0 +1,214 @@
+package native
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// TestCommandCodesMatchPulseAudioProtocol pins the opcodes this backend
+// actually depends on (load/unload module, move sink input, subscribe)
+// against pulse/internal.h's pa_command enum. We can't dial a real
+// PulseAudio/PipeWire socket in CI, so this is the regression guard against
+// the enum silently drifting off those values again, the way collapsing
+// SET_*_VOLUME/SET_*_MUTE into single entries previously did.
+func TestCommandCodesMatchPulseAudioProtocol(t *testing.T) {
+	want := map[string]int{
+		"commandGetSinkInfoList":      22,
+		"commandGetSinkInputInfoList": 30,
+		"commandSubscribe":            35,
+		"commandLoadModule":           51,
+		"commandUnloadModule":         52,
+		"commandSubscribeEvent":       66,
+		"commandMoveSinkInput":        67,
+	}
+	got := map[string]int{
+		"commandGetSinkInfoList":      commandGetSinkInfoList,
+		"commandGetSinkInputInfoList": commandGetSinkInputInfoList,
+		"commandSubscribe":            commandSubscribe,
+		"commandLoadModule":           commandLoadModule,
+		"commandUnloadModule":         commandUnloadModule,
+		"commandSubscribeEvent":       commandSubscribeEvent,
+		"commandMoveSinkInput":        commandMoveSinkInput,
+	}
+	for name, want := range want {
+		if got[name] != want {
+			t.Errorf("%s = %d, want %d", name, got[name], want)
+		}
+	}
+}
+
+// appendSampleSpec writes a raw (untagged) sample_spec, matching how
+// pa_tagstruct_put_sample_spec packs it: format byte, channel-count byte,
+// then a raw u32 rate.
+func appendSampleSpec(w *tagWriter, format, channels byte, rate uint32) {
+	w.buf = append(w.buf, format, channels)
+	var r [4]byte
+	binary.BigEndian.PutUint32(r[:], rate)
+	w.buf = append(w.buf, r[:]...)
+}
+
+// appendChannelMap writes a raw (untagged) channel_map: a channel-count
+// byte followed by one raw position byte per channel.
+func appendChannelMap(w *tagWriter, positions ...byte) {
+	w.buf = append(w.buf, byte(len(positions)))
+	w.buf = append(w.buf, positions...)
+}
+
+// appendCVolume writes a cvolume: a raw channel-count byte followed by one
+// tagged volume per channel.
+func appendCVolume(w *tagWriter, volumes ...uint32) {
+	w.buf = append(w.buf, byte(len(volumes)))
+	for _, v := range volumes {
+		w.buf = append(w.buf, tagVolume)
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], v)
+		w.buf = append(w.buf, b[:]...)
+	}
+}
+
+func appendBoolean(w *tagWriter, v bool) {
+	if v {
+		w.buf = append(w.buf, tagBooleanTrue)
+	} else {
+		w.buf = append(w.buf, tagBooleanFalse)
+	}
+}
+
+func appendUsec(w *tagWriter, v uint64) {
+	w.buf = append(w.buf, tagUsec)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+// appendProplist writes a property list the way pa_tagstruct_put_proplist
+// does: a tag, then (key, declared length, arbitrary value) triples, then a
+// NUL key terminator.
+func appendProplist(w *tagWriter, props map[string]string) {
+	w.buf = append(w.buf, tagProplist)
+	for k, v := range props {
+		w.PutString(k)
+		data := append([]byte(v), 0) // libpulse proplist values are NUL-terminated
+		w.PutU32(uint32(len(data)))
+		w.PutArbitrary(data)
+	}
+	w.PutString("")
+}
+
+// appendSinkInfo writes one GetSinkInfoList record in the same shape
+// readSinkInfo expects to parse, including a couple of ports, so a test can
+// assert the reader doesn't desync on the fields pubtalk ignores.
+func appendSinkInfo(w *tagWriter, idx uint32, name, monitor string) {
+	w.PutU32(idx)
+	w.PutString(name)
+	w.PutString(name + " description")
+	appendSampleSpec(w, 1, 2, 44100)
+	appendChannelMap(w, 1, 2)
+	w.PutU32(7) // owning module index
+	appendCVolume(w, 65536, 65536)
+	appendBoolean(w, false) // muted
+	w.PutU32(idx + 100)     // monitor source index
+	w.PutString(monitor)
+	appendUsec(w, 0) // latency
+	w.PutString("module-null-sink.c")
+	w.PutU32(0) // flags
+	appendProplist(w, map[string]string{"device.description": name})
+	appendUsec(w, 0) // requested latency
+	w.PutU32(65536)  // base volume
+	w.PutU32(0)      // state
+	w.PutU32(65)     // n_volume_steps
+	w.PutU32(0xFFFFFFFF)
+	w.PutU32(2) // n_ports
+	for _, port := range []string{"analog-output", "analog-output-headphones"} {
+		w.PutString(port)
+		w.PutString(port + " description")
+		w.PutU32(1) // priority
+		w.PutU32(0) // availability
+	}
+	w.PutString("analog-output") // active port
+	w.PutU8(0)                   // n_formats
+}
+
+func TestReadSinkInfoStaysAlignedAcrossRecords(t *testing.T) {
+	w := &tagWriter{}
+	appendSinkInfo(w, 1, "virtual-out-meeting", "virtual-out-meeting.monitor")
+	appendSinkInfo(w, 2, "virtual-out-standup", "virtual-out-standup.monitor")
+	tr := newTagReader(w.Bytes())
+
+	first, err := readSinkInfo(tr)
+	if err != nil {
+		t.Fatalf("first record: unexpected error: %v", err)
+	}
+	if first.Index != 1 || first.Name != "virtual-out-meeting" || first.MonitorSource != "virtual-out-meeting.monitor" {
+		t.Fatalf("first record: got %+v", first)
+	}
+
+	second, err := readSinkInfo(tr)
+	if err != nil {
+		t.Fatalf("second record: unexpected error (likely desynced on the first): %v", err)
+	}
+	if second.Index != 2 || second.Name != "virtual-out-standup" || second.MonitorSource != "virtual-out-standup.monitor" {
+		t.Fatalf("second record: got %+v", second)
+	}
+
+	if tr.Remaining() {
+		t.Fatalf("expected no bytes left after two records")
+	}
+}
+
+// appendSinkInputInfo writes one GetSinkInputInfoList record in the shape
+// readSinkInputInfo expects, with application.name/application.process.binary
+// in the proplist, since that's the only place the native protocol carries
+// them.
+func appendSinkInputInfo(w *tagWriter, idx uint32, appName, processBinary string) {
+	w.PutU32(idx)
+	w.PutString("playback stream")
+	w.PutU32(5) // owning module index
+	w.PutU32(3) // client index
+	w.PutU32(1) // sink index
+	appendSampleSpec(w, 1, 2, 44100)
+	appendChannelMap(w, 1, 2)
+	appendCVolume(w, 65536, 65536)
+	appendUsec(w, 1000) // buffer latency
+	appendUsec(w, 2000) // sink latency
+	w.PutString("trivial")
+	w.PutString("protocol-native.c")
+	appendBoolean(w, false) // muted
+	appendProplist(w, map[string]string{
+		"application.name":           appName,
+		"application.process.binary": processBinary,
+		"application.process.id":     "1234",
+	})
+	appendBoolean(w, false) // corked
+	appendBoolean(w, true)  // has_volume
+	appendBoolean(w, true)  // volume_writable
+	w.buf = append(w.buf, tagFormatInfo, 1)
+	appendProplist(w, map[string]string{"format.sample_format": `"s16le"`})
+}
+
+func TestReadSinkInputInfoExtractsProplistAndStaysAligned(t *testing.T) {
+	w := &tagWriter{}
+	appendSinkInputInfo(w, 10, "Firefox", "firefox")
+	appendSinkInputInfo(w, 11, "Zoom", "zoom")
+	tr := newTagReader(w.Bytes())
+
+	first, err := readSinkInputInfo(tr)
+	if err != nil {
+		t.Fatalf("first record: unexpected error: %v", err)
+	}
+	if first.ID != 10 || first.ApplicationName != "Firefox" || first.ProcessBinary != "firefox" {
+		t.Fatalf("first record: got %+v", first)
+	}
+
+	second, err := readSinkInputInfo(tr)
+	if err != nil {
+		t.Fatalf("second record: unexpected error (likely desynced on the first): %v", err)
+	}
+	if second.ID != 11 || second.ApplicationName != "Zoom" || second.ProcessBinary != "zoom" {
+		t.Fatalf("second record: got %+v", second)
+	}
+
+	if tr.Remaining() {
+		t.Fatalf("expected no bytes left after two records")
+	}
+}
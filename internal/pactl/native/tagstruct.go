@@ -0,0 +1,297 @@
+package native
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Tag bytes for the subset of the PulseAudio tagstruct wire format this
+// backend needs. See pulse/tagstruct.h upstream for the full list.
+const (
+	tagString       = 't'
+	tagStringNUL    = 'N'
+	tagU32          = 'L'
+	tagU8           = 'B'
+	tagArbitrary    = 'x'
+	tagBooleanTrue  = '1'
+	tagBooleanFalse = '0'
+	tagUsec         = 'U'
+	tagVolume       = 'V'
+	tagProplist     = 'P'
+	tagFormatInfo   = 'f'
+)
+
+// tagWriter builds a tagstruct-encoded command payload.
+type tagWriter struct {
+	buf []byte
+}
+
+func (w *tagWriter) PutU32(v uint32) {
+	var b [5]byte
+	b[0] = tagU32
+	binary.BigEndian.PutUint32(b[1:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *tagWriter) PutU8(v uint8) {
+	w.buf = append(w.buf, tagU8, v)
+}
+
+func (w *tagWriter) PutString(s string) {
+	if s == "" {
+		w.buf = append(w.buf, tagStringNUL)
+		return
+	}
+	w.buf = append(w.buf, tagString)
+	w.buf = append(w.buf, []byte(s)...)
+	w.buf = append(w.buf, 0)
+}
+
+func (w *tagWriter) PutArbitrary(data []byte) {
+	var lenb [4]byte
+	binary.BigEndian.PutUint32(lenb[:], uint32(len(data)))
+	w.buf = append(w.buf, tagArbitrary)
+	w.buf = append(w.buf, lenb[:]...)
+	w.buf = append(w.buf, data...)
+}
+
+func (w *tagWriter) Bytes() []byte { return w.buf }
+
+// tagReader parses a tagstruct-encoded reply payload.
+type tagReader struct {
+	buf []byte
+	pos int
+}
+
+func newTagReader(buf []byte) *tagReader { return &tagReader{buf: buf} }
+
+func (r *tagReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, fmt.Errorf("tagstruct: unexpected end of buffer")
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *tagReader) GetU32() (uint32, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagU32 {
+		return 0, fmt.Errorf("tagstruct: expected u32 tag, got %q", tag)
+	}
+	if r.pos+4 > len(r.buf) {
+		return 0, fmt.Errorf("tagstruct: truncated u32")
+	}
+	v := binary.BigEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *tagReader) GetU8() (uint8, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagU8 {
+		return 0, fmt.Errorf("tagstruct: expected u8 tag, got %q", tag)
+	}
+	return r.byte()
+}
+
+// GetString reads a string tag, returning "" for a NUL (empty) string.
+func (r *tagReader) GetString() (string, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	switch tag {
+	case tagStringNUL:
+		return "", nil
+	case tagString:
+		start := r.pos
+		for r.pos < len(r.buf) && r.buf[r.pos] != 0 {
+			r.pos++
+		}
+		if r.pos >= len(r.buf) {
+			return "", fmt.Errorf("tagstruct: unterminated string")
+		}
+		s := string(r.buf[start:r.pos])
+		r.pos++ // skip NUL
+		return s, nil
+	default:
+		return "", fmt.Errorf("tagstruct: expected string tag, got %q", tag)
+	}
+}
+
+// GetArbitrary reads an arbitrary (opaque, length-prefixed) byte blob.
+func (r *tagReader) GetArbitrary() ([]byte, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagArbitrary {
+		return nil, fmt.Errorf("tagstruct: expected arbitrary tag, got %q", tag)
+	}
+	if r.pos+4 > len(r.buf) {
+		return nil, fmt.Errorf("tagstruct: truncated arbitrary length")
+	}
+	n := binary.BigEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	if r.pos+int(n) > len(r.buf) {
+		return nil, fmt.Errorf("tagstruct: truncated arbitrary data")
+	}
+	data := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return data, nil
+}
+
+func (r *tagReader) GetBoolean() (bool, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return false, err
+	}
+	switch tag {
+	case tagBooleanTrue:
+		return true, nil
+	case tagBooleanFalse:
+		return false, nil
+	default:
+		return false, fmt.Errorf("tagstruct: expected boolean tag, got %q", tag)
+	}
+}
+
+// GetUsec reads a 64-bit microsecond duration (latencies use this type).
+func (r *tagReader) GetUsec() (uint64, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagUsec {
+		return 0, fmt.Errorf("tagstruct: expected usec tag, got %q", tag)
+	}
+	if r.pos+8 > len(r.buf) {
+		return 0, fmt.Errorf("tagstruct: truncated usec")
+	}
+	v := binary.BigEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+
+func (r *tagReader) GetVolume() (uint32, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagVolume {
+		return 0, fmt.Errorf("tagstruct: expected volume tag, got %q", tag)
+	}
+	if r.pos+4 > len(r.buf) {
+		return 0, fmt.Errorf("tagstruct: truncated volume")
+	}
+	v := binary.BigEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// SkipSampleSpec consumes a sample_spec: format and channel count each as a
+// single raw (untagged) byte, followed by a raw (untagged) u32 sample rate.
+// Unlike most fields in a reply, these are packed back-to-back without
+// individual tag bytes.
+func (r *tagReader) SkipSampleSpec() error {
+	if _, err := r.byte(); err != nil { // format
+		return err
+	}
+	if _, err := r.byte(); err != nil { // channels
+		return err
+	}
+	if r.pos+4 > len(r.buf) {
+		return fmt.Errorf("tagstruct: truncated sample spec rate")
+	}
+	r.pos += 4
+	return nil
+}
+
+// SkipChannelMap consumes a channel_map: a raw channel-count byte followed
+// by one raw channel-position byte per channel.
+func (r *tagReader) SkipChannelMap() error {
+	n, err := r.byte()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(n); i++ {
+		if _, err := r.byte(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipCVolume consumes a cvolume: a raw channel-count byte followed by one
+// tagged volume per channel.
+func (r *tagReader) SkipCVolume() error {
+	n, err := r.byte()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(n); i++ {
+		if _, err := r.GetVolume(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProplist reads a property list into a string-keyed map. Values are
+// decoded as strings, trimming the trailing NUL libpulse's own proplist
+// values are conventionally terminated with.
+func (r *tagReader) GetProplist() (map[string]string, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagProplist {
+		return nil, fmt.Errorf("tagstruct: expected proplist tag, got %q", tag)
+	}
+
+	props := make(map[string]string)
+	for {
+		key, err := r.GetString()
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return props, nil
+		}
+		if _, err := r.GetU32(); err != nil { // declared value length, redundant with GetArbitrary's own
+			return nil, err
+		}
+		value, err := r.GetArbitrary()
+		if err != nil {
+			return nil, err
+		}
+		props[key] = strings.TrimRight(string(value), "\x00")
+	}
+}
+
+// SkipFormatInfo consumes a format_info: its tag, a raw encoding byte, then
+// the proplist of its format-specific parameters.
+func (r *tagReader) SkipFormatInfo() error {
+	tag, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if tag != tagFormatInfo {
+		return fmt.Errorf("tagstruct: expected format info tag, got %q", tag)
+	}
+	if _, err := r.byte(); err != nil { // encoding
+		return err
+	}
+	_, err = r.GetProplist()
+	return err
+}
+
+func (r *tagReader) Remaining() bool { return r.pos < len(r.buf) }
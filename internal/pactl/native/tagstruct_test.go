@@ -0,0 +1,41 @@
+package native
+
+import "testing"
+
+func TestTagWriterReaderRoundTrip(t *testing.T) {
+	w := &tagWriter{}
+	w.PutU32(42)
+	w.PutString("sink-virtual-out")
+	w.PutString("")
+	w.PutU8(7)
+	w.PutArbitrary([]byte{1, 2, 3})
+
+	r := newTagReader(w.Bytes())
+
+	u, err := r.GetU32()
+	if err != nil || u != 42 {
+		t.Fatalf("GetU32: got %d, %v", u, err)
+	}
+	s, err := r.GetString()
+	if err != nil || s != "sink-virtual-out" {
+		t.Fatalf("GetString: got %q, %v", s, err)
+	}
+	empty, err := r.GetString()
+	if err != nil || empty != "" {
+		t.Fatalf("GetString (empty): got %q, %v", empty, err)
+	}
+	b, err := r.GetU8()
+	if err != nil || b != 7 {
+		t.Fatalf("GetU8: got %d, %v", b, err)
+	}
+}
+
+func TestTagReaderRejectsWrongTag(t *testing.T) {
+	w := &tagWriter{}
+	w.PutString("not a number")
+	r := newTagReader(w.Bytes())
+
+	if _, err := r.GetU32(); err == nil {
+		t.Fatal("expected error reading u32 from a string tag")
+	}
+}
@@ -49,6 +49,7 @@ func (c *Client) Pactl(args ...string) ([]byte, error) {
 type SinkInput struct {
 	ID              int
 	ApplicationName string
+	ProcessBinary   string
 }
 
 // ListSinkInputs fetches all current sink inputs and their properties.
@@ -79,6 +80,12 @@ func (c *Client) ListSinkInputs() ([]SinkInput, error) {
 			if len(parts) == 2 {
 				currentInput.ApplicationName = strings.Trim(strings.TrimSpace(parts[1]), `"`)
 			}
+		} else if currentInput != nil && strings.HasPrefix(line, "application.process.binary =") {
+			// e.g. application.process.binary = "firefox"
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				currentInput.ProcessBinary = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			}
 		}
 	}
 	if currentInput != nil {
@@ -195,6 +202,55 @@ func (c *Client) UnloadModule(moduleID int) error {
 	return nil
 }
 
+// ServerType identifies whether the connected server is PipeWire's
+// PulseAudio-compatible layer or a vanilla PulseAudio daemon.
+type ServerType int
+
+const (
+	ServerPulseAudio ServerType = iota
+	ServerPipeWire
+)
+
+func (t ServerType) String() string {
+	if t == ServerPipeWire {
+		return "PipeWire"
+	}
+	return "PulseAudio"
+}
+
+// ServerInfo describes the audio server pubtalk is talking to.
+type ServerInfo struct {
+	Type       ServerType
+	ServerName string
+}
+
+// ServerInfo queries the server's identity via `pactl info`. The server
+// name is "pulseaudio" on a vanilla PulseAudio daemon and something like
+// "PulseAudio (on PipeWire 1.0.5)" when running on PipeWire's compatibility
+// layer; we use that to pick module topologies that work on both.
+func (c *Client) ServerInfo() (ServerInfo, error) {
+	out, err := c.Pactl("info")
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("failed to query server info: %w", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "Server Name: ") {
+			continue
+		}
+		name := strings.TrimPrefix(line, "Server Name: ")
+		info := ServerInfo{ServerName: name, Type: ServerPulseAudio}
+		if strings.Contains(name, "PipeWire") {
+			info.Type = ServerPipeWire
+		}
+		return info, nil
+	}
+
+	return ServerInfo{}, fmt.Errorf("could not find 'Server Name' in pactl info output")
+}
+
 // SinkExists checks if a sink with the given name already exists.
 func (c *Client) SinkExists(sinkName string) (bool, error) {
 	out, err := c.Pactl("list", "short", "sinks")
@@ -0,0 +1,202 @@
+package pactl
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PactlBackend implements Backend by shelling out to the pactl CLI via a
+// Client. It is the fallback used whenever the native protocol socket isn't
+// reachable (e.g. remote sessions, sandboxes without $XDG_RUNTIME_DIR/pulse).
+type PactlBackend struct {
+	Client *Client
+}
+
+// NewPactlBackend wraps an existing Client as a Backend.
+func NewPactlBackend(c *Client) *PactlBackend {
+	return &PactlBackend{Client: c}
+}
+
+// ServerInfo returns the server's identity (used to pick a module topology).
+func (b *PactlBackend) ServerInfo() (ServerInfo, error) {
+	return b.Client.ServerInfo()
+}
+
+// ListSinks returns every sink known to the server.
+func (b *PactlBackend) ListSinks() ([]Sink, error) {
+	out, err := b.Client.Pactl("list", "sinks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sinks: %w", err)
+	}
+
+	var sinks []Sink
+	var cur *Sink
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "Sink #"):
+			if cur != nil {
+				sinks = append(sinks, *cur)
+			}
+			idx, _ := strconv.Atoi(strings.TrimPrefix(line, "Sink #"))
+			cur = &Sink{Index: idx}
+		case cur != nil && strings.HasPrefix(line, "Name: "):
+			cur.Name = strings.TrimPrefix(line, "Name: ")
+		case cur != nil && strings.HasPrefix(line, "Monitor Source: "):
+			cur.MonitorSource = strings.TrimPrefix(line, "Monitor Source: ")
+		}
+	}
+	if cur != nil {
+		sinks = append(sinks, *cur)
+	}
+	return sinks, nil
+}
+
+// ListModules returns every loaded module with its id, name and argument
+// string. NUsed is set to -1: plain pactl output doesn't expose a module's
+// in-use count, only native.Backend can report it reliably.
+func (b *PactlBackend) ListModules() ([]Module, error) {
+	out, err := b.Client.Pactl("list", "short", "modules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	var modules []Module
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 2 {
+			continue
+		}
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		m := Module{ID: id, Name: parts[1], NUsed: -1}
+		if len(parts) >= 3 {
+			m.Argument = parts[2]
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+// ListSinkInputs returns every current sink input.
+func (b *PactlBackend) ListSinkInputs() ([]SinkInput, error) {
+	return b.Client.ListSinkInputs()
+}
+
+// LoadModule loads a module and returns the new module's id.
+func (b *PactlBackend) LoadModule(name string, args ...string) (int, error) {
+	out, err := b.Client.Pactl(append([]string{"load-module", name}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load module '%s': %w", name, err)
+	}
+	if b.Client.DryRun {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse module id from load-module output %q: %w", out, err)
+	}
+	return id, nil
+}
+
+// UnloadModule unloads a module by id.
+func (b *PactlBackend) UnloadModule(id int) error {
+	return b.Client.UnloadModule(id)
+}
+
+// MoveSinkInput moves a sink input to a different sink.
+func (b *PactlBackend) MoveSinkInput(id int, sink string) error {
+	return b.Client.MoveSinkInput(id, sink)
+}
+
+var subscribeEventRe = regexp.MustCompile(`^Event '(\w+)' on (sink|source|sink-input|source-output|module|client|server)(?: #(\d+))?`)
+
+// Subscribe runs `pactl subscribe` as a long-lived child process and parses
+// its output into Events. The returned channel is closed when the process
+// exits; callers that want to keep listening must restart the subscription.
+func (b *PactlBackend) Subscribe() (<-chan Event, error) {
+	events := make(chan Event)
+	if b.Client.DryRun {
+		close(events)
+		return events, nil
+	}
+
+	cmd := exec.Command("pactl", "subscribe")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pactl subscribe stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pactl subscribe: %w", err)
+	}
+
+	go func() {
+		defer close(events)
+		defer cmd.Wait()
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if ev, ok := parseSubscribeLine(scanner.Text()); ok {
+				events <- ev
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func parseSubscribeLine(line string) (Event, bool) {
+	m := subscribeEventRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Event{}, false
+	}
+
+	var ev Event
+	switch m[1] {
+	case "new":
+		ev.Type = EventNew
+	case "change":
+		ev.Type = EventChange
+	case "remove":
+		ev.Type = EventRemove
+	default:
+		return Event{}, false
+	}
+
+	switch m[2] {
+	case "sink":
+		ev.Facility = FacilitySink
+	case "source":
+		ev.Facility = FacilitySource
+	case "sink-input":
+		ev.Facility = FacilitySinkInput
+	case "source-output":
+		ev.Facility = FacilitySourceOutput
+	case "module":
+		ev.Facility = FacilityModule
+	case "client":
+		ev.Facility = FacilityClient
+	case "server":
+		ev.Facility = FacilityServer
+	default:
+		return Event{}, false
+	}
+
+	if m[3] != "" {
+		idx, err := strconv.ParseUint(m[3], 10, 32)
+		if err != nil {
+			return Event{}, false
+		}
+		ev.Index = uint32(idx)
+	}
+
+	return ev, true
+}
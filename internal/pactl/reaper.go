@@ -0,0 +1,45 @@
+package pactl
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReapChildProcs installs a SIGCHLD handler that reaps any exited child
+// process nothing else is explicitly waiting on - e.g. a `pactl subscribe`
+// invocation (see PactlBackend.Subscribe) that outlived the code reading
+// its events, so its exit was never collected with an explicit Wait. This
+// mirrors the reaper pattern tools like oz use for their supervised
+// children. Call the returned stop function to uninstall the handler.
+func ReapChildProcs() (stop func()) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGCHLD)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-sigChan:
+				reapExited()
+			case <-done:
+				signal.Stop(sigChan)
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// reapExited drains every already-exited child process, stopping once
+// Wait4 reports nothing left to reap.
+func reapExited() {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err != nil || pid <= 0 {
+			return
+		}
+	}
+}
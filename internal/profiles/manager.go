@@ -7,35 +7,188 @@ import (
 	"gemini-audio/internal/pactl"
 )
 
-// Manager implements profile deployment for Ubuntu 24.04 / PipeWire.
-// Implementation notes:
+// Manager implements profile deployment for Ubuntu 24.04, targeting either
+// PipeWire (via its PulseAudio compatibility layer) or a vanilla PulseAudio
+// daemon. Implementation notes:
 // - We create a virtual null-sink for the profile's output (`virtual-out-...`).
 // - We create a second null-sink to act as the visible microphone (`...-mic`).
 // - A loopback module feeds the first sink's monitor into the mic sink, and apps
-//   select the mic sink's `.monitor` as their recording source. This works reliably
-//   under PipeWire's PulseAudio compatibility layer and avoids creating ambiguous
+//   select the mic sink's `.monitor` as their recording source. This works
+//   reliably on both PipeWire and PulseAudio and avoids creating ambiguous
 //   remapped sources which sometimes appear as duplicates.
+// - Module topologies that differ between the two servers (currently just
+//   the noise-suppression chain) are picked based on Manager.serverType,
+//   detected once per Manager via Backend.ServerInfo.
+//
+// Manager talks to the server through a pactl.Backend so it works identically
+// whether that backend shells out to pactl (pactl.PactlBackend) or speaks the
+// native protocol directly (native.Backend).
 
 // Manager handles deployment and reset of audio profiles
 type Manager struct {
-	client *pactl.Client
+	backend pactl.Backend
+
+	serverTypeKnown bool
+	serverType      pactl.ServerType
+}
+
+// NewManager creates a new profile manager backed by the given Backend.
+func NewManager(backend pactl.Backend) *Manager {
+	return &Manager{backend: backend}
+}
+
+// detectServerType queries the backend for the server type on first use and
+// caches the result for the lifetime of the Manager.
+func (m *Manager) detectServerType() (pactl.ServerType, error) {
+	if m.serverTypeKnown {
+		return m.serverType, nil
+	}
+	info, err := m.backend.ServerInfo()
+	if err != nil {
+		return 0, fmt.Errorf("failed to detect server type: %w", err)
+	}
+	m.serverType = info.Type
+	m.serverTypeKnown = true
+	return m.serverType, nil
+}
+
+func (m *Manager) sinkExists(name string) (bool, error) {
+	sinks, err := m.backend.ListSinks()
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sinks {
+		if s.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Manager) sinkMonitor(name string) (string, error) {
+	sinks, err := m.backend.ListSinks()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range sinks {
+		if s.Name == name {
+			return s.MonitorSource, nil
+		}
+	}
+	return "", fmt.Errorf("monitor source for sink '%s' not found", name)
+}
+
+// findModulesMatching returns the ids of loaded modules whose argument
+// string contains every substring in substrs.
+func (m *Manager) findModulesMatching(substrs ...string) ([]int, error) {
+	modules, err := m.backend.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, mod := range modules {
+		matchesAll := true
+		for _, s := range substrs {
+			if !strings.Contains(mod.Argument, s) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			ids = append(ids, mod.ID)
+		}
+	}
+	return ids, nil
 }
 
-// NewManager creates a new profile manager
-func NewManager(client *pactl.Client) *Manager {
-	return &Manager{client: client}
+// unloadMatching finds modules whose argument string contains every
+// substring in substrs and unloads them, logging under label. It returns an
+// aggregated error for any that failed to unload, so callers (e.g. the
+// crash-safety journal) don't mistake a partial teardown for a clean one.
+func (m *Manager) unloadMatching(label string, substrs ...string) error {
+	ids, err := m.findModulesMatching(substrs...)
+	if err != nil {
+		return fmt.Errorf("failed to find %s modules: %w", label, err)
+	}
+	if len(ids) == 0 {
+		fmt.Printf("     - No %s found\n", label)
+		return nil
+	}
+	m.warnIfInUse(label, ids)
+	fmt.Printf("     - Unloading %d %s module(s)\n", len(ids), label)
+	var errs []error
+	for _, modID := range ids {
+		if err := m.backend.UnloadModule(modID); err != nil {
+			fmt.Printf("       Warning: failed to unload %s module %d: %v\n", label, modID, err)
+			errs = append(errs, fmt.Errorf("%s module %d: %w", label, modID, err))
+		} else {
+			fmt.Printf("       Unloaded %s module %d\n", label, modID)
+		}
+	}
+	return joinErrors(errs)
 }
 
-// Deploy applies a profile configuration
+// warnIfInUse logs a warning for any of ids whose server-reported NUsed
+// counter is above zero, so an operator sees a live stream is about to be
+// disconnected before it happens. NUsed is -1 (unknown) for backends that
+// can't report it, e.g. PactlBackend, in which case there's nothing to warn
+// about.
+func (m *Manager) warnIfInUse(label string, ids []int) {
+	modules, err := m.backend.ListModules()
+	if err != nil {
+		return
+	}
+	nUsed := make(map[int]int, len(modules))
+	for _, mod := range modules {
+		nUsed[mod.ID] = mod.NUsed
+	}
+	for _, id := range ids {
+		if n, ok := nUsed[id]; ok && n > 0 {
+			fmt.Printf("     - Warning: %s module %d is still in use (n_used=%d), unloading anyway\n", label, id, n)
+		}
+	}
+}
+
+// joinErrors combines errs into a single error, or returns nil if errs is
+// empty.
+func joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return fmt.Errorf("%d module(s) failed to unload: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+// Deploy applies a profile configuration: it creates the module topology
+// (idempotently, see ensureTopology) and then sweeps currently-running
+// applications for ones to route onto it.
 func (m *Manager) Deploy(p *Profile) error {
+	if err := m.ensureTopology(p); err != nil {
+		return err
+	}
+	return m.routeApplications(p)
+}
+
+// ensureTopology creates whatever modules a profile expects that aren't
+// already loaded. It's idempotent: calling it on a Loaded profile is a
+// no-op, which is what lets Deploy and Reconcile share it.
+func (m *Manager) ensureTopology(p *Profile) error {
+	serverType, err := m.detectServerType()
+	if err != nil {
+		return err
+	}
+
 	// 1. Create the main virtual sink, if it doesn't exist
-	exists, err := m.client.SinkExists(p.VirtualSink)
+	exists, err := m.sinkExists(p.VirtualSink)
 	if err != nil {
 		return fmt.Errorf("could not check for sink '%s': %w", p.VirtualSink, err)
 	}
 	if !exists {
-		_, err = m.client.Pactl("load-module", "module-null-sink", fmt.Sprintf("sink_name=%s", p.VirtualSink))
-		if err != nil {
+		if _, err := m.backend.LoadModule("module-null-sink", "sink_name="+p.VirtualSink); err != nil {
 			return fmt.Errorf("failed to create virtual sink '%s': %w", p.VirtualSink, err)
 		}
 		fmt.Printf("     - Created virtual sink: %s\n", p.VirtualSink)
@@ -48,44 +201,66 @@ func (m *Manager) Deploy(p *Profile) error {
 	micSinkName := p.VirtualSink + "-mic"
 	micDescription := "Virtual Mic (" + p.Name + ")"
 
-	exists, err = m.client.SinkExists(micSinkName)
-	if err != nil {
-		return fmt.Errorf("could not check for mic sink '%s': %w", micSinkName, err)
-	}
-
-	if !exists {
-		// Create a null sink for the microphone
-		_, err = m.client.Pactl("load-module", "module-null-sink", "sink_name="+micSinkName, fmt.Sprintf(`sink_properties="device.description='%s' device.icon_name=audio-input-microphone"`, micDescription))
+	if p.NoiseSuppression != nil {
+		var err error
+		if serverType == pactl.ServerPipeWire {
+			err = m.deployNoiseSuppressionPipeWire(p, micSinkName, micDescription)
+		} else {
+			err = m.deployNoiseSuppressionLegacy(p, micDescription)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to create virtual mic sink '%s': %w", micSinkName, err)
+			return err
+		}
+	} else {
+		exists, err = m.sinkExists(micSinkName)
+		if err != nil {
+			return fmt.Errorf("could not check for mic sink '%s': %w", micSinkName, err)
 		}
-		fmt.Printf("     - Created virtual mic: %s\n", micDescription)
-	}
 
-	// Create a loopback that feeds the main sink's audio into this mic sink
-	monitorSource, err := m.client.GetSinkMonitor(p.VirtualSink)
-	if err != nil {
-		return fmt.Errorf("failed to get monitor source for '%s': %w", p.VirtualSink, err)
-	}
+		if !exists {
+			// Create a null sink for the microphone
+			_, err := m.backend.LoadModule("module-null-sink", "sink_name="+micSinkName,
+				fmt.Sprintf(`sink_properties="device.description='%s' device.icon_name=audio-input-microphone"`, micDescription))
+			if err != nil {
+				return fmt.Errorf("failed to create virtual mic sink '%s': %w", micSinkName, err)
+			}
+			fmt.Printf("     - Created virtual mic: %s\n", micDescription)
+		}
 
-	// Create loopback with low latency for real-time monitoring.
-	// Avoid creating duplicates by checking for an existing module with both source and sink args.
-	loopArgs := []string{"source=" + monitorSource, "sink=" + micSinkName}
-	if _, err := m.client.FindModulesMatching(loopArgs); err != nil {
-		_, err = m.client.Pactl("load-module", "module-loopback", "source="+monitorSource, "sink="+micSinkName, "latency_msec=1")
+		// Create a loopback that feeds the main sink's audio into this mic sink
+		monitorSource, err := m.sinkMonitor(p.VirtualSink)
 		if err != nil {
-			return fmt.Errorf("failed to create loopback: %w", err)
+			return fmt.Errorf("failed to get monitor source for '%s': %w", p.VirtualSink, err)
+		}
+
+		// Create loopback with low latency for real-time monitoring.
+		// Avoid creating duplicates by checking for an existing module with both source and sink args.
+		loopArgs := []string{"source=" + monitorSource, "sink=" + micSinkName}
+		if ids, err := m.findModulesMatching(loopArgs...); err != nil || len(ids) == 0 {
+			if _, err := m.backend.LoadModule("module-loopback", "source="+monitorSource, "sink="+micSinkName, "latency_msec=1"); err != nil {
+				return fmt.Errorf("failed to create loopback: %w", err)
+			}
+			fmt.Printf("     - Created loopback: %s -> %s\n", monitorSource, micSinkName)
+		} else {
+			fmt.Printf("     - Loopback already exists for %s -> %s, skipping\n", monitorSource, micSinkName)
 		}
-		fmt.Printf("     - Created loopback: %s -> %s\n", monitorSource, micSinkName)
-	} else {
-		fmt.Printf("     - Loopback already exists for %s -> %s, skipping\n", monitorSource, micSinkName)
 	}
 
-	// 4. Route applications
+	return nil
+}
+
+// routeApplications sweeps currently-running sink inputs for ones matching a
+// profile's playback applications and moves them onto the virtual sink, and
+// logs guidance for input_target applications. This is the one-shot
+// counterpart to Router, which does the same matching reactively as new sink
+// inputs appear.
+func (m *Manager) routeApplications(p *Profile) error {
+	micDescription := "Virtual Mic (" + p.Name + ")"
+
 	for _, app := range p.Applications {
 		if app.Role == "playback" {
 			fmt.Printf("     - Routing playback for: %s\n", app.Name)
-			inputs, err := m.client.ListSinkInputs()
+			inputs, err := m.backend.ListSinkInputs()
 			if err != nil {
 				fmt.Printf("       Warning: failed to list sink inputs: %v\n", err)
 				continue
@@ -95,7 +270,7 @@ func (m *Manager) Deploy(p *Profile) error {
 			for _, input := range inputs {
 				if strings.Contains(strings.ToLower(input.ApplicationName), strings.ToLower(app.Name)) {
 					fmt.Printf("       Found sink input #%d (%s)\n", input.ID, input.ApplicationName)
-					err := m.client.MoveSinkInput(input.ID, p.VirtualSink)
+					err := m.backend.MoveSinkInput(input.ID, p.VirtualSink)
 					if err != nil {
 						fmt.Printf("       Warning: failed to move sink input: %v\n", err)
 					} else {
@@ -117,58 +292,183 @@ func (m *Manager) Deploy(p *Profile) error {
 	return nil
 }
 
-// Reset removes all modules associated with a profile
-func (m *Manager) Reset(p *Profile) error {
-	micSinkName := p.VirtualSink + "-mic"
+// deployNoiseSuppressionPipeWire inserts a LADSPA filter between the virtual
+// sink's monitor and the profile's mic, instead of the plain null-sink +
+// loopback pair. On PipeWire this is a single module-ladspa-source that
+// exposes the filtered stream directly as the mic's recording source.
+func (m *Manager) deployNoiseSuppressionPipeWire(p *Profile, micSinkName, micDescription string) error {
+	ns := p.NoiseSuppression
 
-	// 1. Unload loopback modules that feed audio into the mic sink
-	// Find loopback modules matching both the monitor source and mic sink to avoid unloading unrelated modules
-	monitorSource, _ := m.client.GetSinkMonitor(p.VirtualSink)
-	loopArgs := []string{"source=" + monitorSource, "sink=" + micSinkName}
-	loopbackModIDs, err := m.client.FindModulesMatching(loopArgs)
+	monitorSource, err := m.sinkMonitor(p.VirtualSink)
 	if err != nil {
-		fmt.Printf("     - No loopback modules found for %s -> %s\n", monitorSource, micSinkName)
-	} else {
-		fmt.Printf("     - Unloading %d loopback module(s)\n", len(loopbackModIDs))
-		for _, modID := range loopbackModIDs {
-			if err := m.client.UnloadModule(modID); err != nil {
-				fmt.Printf("       Warning: failed to unload loopback module %d: %v\n", modID, err)
-			} else {
-				fmt.Printf("       Unloaded loopback module %d\n", modID)
-			}
-		}
+		return fmt.Errorf("failed to get monitor source for '%s': %w", p.VirtualSink, err)
+	}
+
+	if ids, err := m.findModulesMatching("source_name="+micSinkName, "plugin="+ns.Plugin); err == nil && len(ids) > 0 {
+		fmt.Printf("     - LADSPA noise-suppression source already exists for %s, skipping\n", micSinkName)
+		return nil
+	}
+
+	args := []string{
+		"source_name=" + micSinkName,
+		"source_master=" + monitorSource,
+		"plugin=" + ns.Plugin,
+		"label=" + ns.Label,
+		fmt.Sprintf(`source_properties="device.description='%s' device.icon_name=audio-input-microphone"`, micDescription),
+	}
+	if len(ns.Control) > 0 {
+		args = append(args, "control="+strings.Join(ns.Control, ","))
+	}
+
+	if _, err := m.backend.LoadModule("module-ladspa-source", args...); err != nil {
+		return fmt.Errorf("failed to load LADSPA noise-suppression source '%s': %w", micSinkName, err)
 	}
+	fmt.Printf("     - Created LADSPA noise-suppression mic: %s (%s/%s)\n", micDescription, ns.Plugin, ns.Label)
+	return nil
+}
+
+// deployNoiseSuppressionLegacy builds the classic two-stage LADSPA chain
+// (the NoiseTorch technique) for plain PulseAudio servers that don't support
+// module-ladspa-source: a null-sink to hold the denoised output, a
+// module-ladspa-sink that filters into it, and a loopback feeding the
+// profile's monitor into the filter's input.
+func (m *Manager) deployNoiseSuppressionLegacy(p *Profile, micDescription string) error {
+	ns := p.NoiseSuppression
+	denoisedOut := p.Name + "_denoised_out"
+	rawIn := p.Name + "_raw_in"
 
-	// 2. Unload the mic sink modules
-	nullSinkArg := "sink_name=" + micSinkName
-	nullSinkModIDs, err := m.client.FindModules(nullSinkArg)
+	monitorSource, err := m.sinkMonitor(p.VirtualSink)
 	if err != nil {
-		fmt.Printf("     - No mic sink modules found\n")
-	} else {
-		fmt.Printf("     - Unloading %d mic sink module(s)\n", len(nullSinkModIDs))
-		for _, modID := range nullSinkModIDs {
-			if err := m.client.UnloadModule(modID); err != nil {
-				fmt.Printf("       Warning: failed to unload mic sink module %d: %v\n", modID, err)
-			} else {
-				fmt.Printf("       Unloaded mic sink module %d\n", modID)
+		return fmt.Errorf("failed to get monitor source for '%s': %w", p.VirtualSink, err)
+	}
+
+	if ids, err := m.findModulesMatching("sink_name=" + rawIn); err == nil && len(ids) > 0 {
+		fmt.Printf("     - Legacy LADSPA noise-suppression chain already exists for %s, skipping\n", p.Name)
+		return nil
+	}
+
+	if _, err := m.backend.LoadModule("module-null-sink", "sink_name="+denoisedOut,
+		fmt.Sprintf(`sink_properties="device.description='%s' device.icon_name=audio-input-microphone"`, micDescription)); err != nil {
+		return fmt.Errorf("failed to create denoised-out sink '%s': %w", denoisedOut, err)
+	}
+
+	args := []string{
+		"sink_name=" + rawIn,
+		"sink_master=" + denoisedOut,
+		"plugin=" + ns.Plugin,
+		"label=" + ns.Label,
+	}
+	if len(ns.Control) > 0 {
+		args = append(args, "control="+strings.Join(ns.Control, ","))
+	}
+	if _, err := m.backend.LoadModule("module-ladspa-sink", args...); err != nil {
+		return fmt.Errorf("failed to create LADSPA sink '%s': %w", rawIn, err)
+	}
+
+	if _, err := m.backend.LoadModule("module-loopback", "source="+monitorSource, "sink="+rawIn, "latency_msec=1"); err != nil {
+		return fmt.Errorf("failed to create loopback into '%s': %w", rawIn, err)
+	}
+
+	fmt.Printf("     - Created legacy LADSPA noise-suppression chain for %s: %s -> %s -> %s\n", micDescription, monitorSource, rawIn, denoisedOut)
+	return nil
+}
+
+// ModuleIDsFor returns the ids of every module currently loaded for a
+// profile's topology (main sink, plus whichever mic/noise-suppression chain
+// applies). Callers use this after a successful Deploy to record the ids in
+// the crash-safety journal.
+func (m *Manager) ModuleIDsFor(p *Profile) ([]int, error) {
+	var ids []int
+
+	mainIDs, err := m.findModulesMatching("sink_name=" + p.VirtualSink)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, mainIDs...)
+
+	micSinkName := p.VirtualSink + "-mic"
+
+	if p.NoiseSuppression != nil {
+		pwIDs, err := m.findModulesMatching("source_name="+micSinkName, "plugin="+p.NoiseSuppression.Plugin)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, pwIDs...)
+
+		denoisedOut := p.Name + "_denoised_out"
+		rawIn := p.Name + "_raw_in"
+		for _, substr := range []string{"sink_name=" + denoisedOut, "sink_name=" + rawIn, "sink=" + rawIn} {
+			legacyIDs, err := m.findModulesMatching(substr)
+			if err != nil {
+				return nil, err
 			}
+			ids = append(ids, legacyIDs...)
 		}
+		return ids, nil
 	}
 
-	// 3. Unload main sink modules
-	mainModIDs, err := m.client.FindModules("sink_name=" + p.VirtualSink)
+	micIDs, err := m.findModulesMatching("sink_name=" + micSinkName)
 	if err != nil {
-		fmt.Printf("     - No main sink modules found\n")
+		return nil, err
+	}
+	ids = append(ids, micIDs...)
+
+	if monitorSource, err := m.sinkMonitor(p.VirtualSink); err == nil {
+		if loopIDs, err := m.findModulesMatching("source="+monitorSource, "sink="+micSinkName); err == nil {
+			ids = append(ids, loopIDs...)
+		}
+	}
+
+	return ids, nil
+}
+
+// Reset removes all modules associated with a profile. It returns an
+// aggregated error for any modules that failed to unload - callers that
+// persist deployment state (the crash-safety journal) must not drop their
+// record of a module until this confirms it's actually gone.
+func (m *Manager) Reset(p *Profile) error {
+	micSinkName := p.VirtualSink + "-mic"
+	var errs []error
+
+	if p.NoiseSuppression != nil {
+		// Tear down whichever topology was actually loaded. This doesn't
+		// depend on re-detecting the server type (which may not even be
+		// reachable during cleanup), since unloading a pattern that was
+		// never loaded is a harmless no-op.
+		if err := m.unloadMatching(fmt.Sprintf("LADSPA noise-suppression source for %s", micSinkName), "source_name="+micSinkName); err != nil {
+			errs = append(errs, err)
+		}
+
+		denoisedOut := p.Name + "_denoised_out"
+		rawIn := p.Name + "_raw_in"
+		if err := m.unloadMatching("legacy noise-suppression loopback", "sink="+rawIn); err != nil {
+			errs = append(errs, err)
+		}
+		if err := m.unloadMatching("legacy noise-suppression LADSPA sink", "sink_name="+rawIn); err != nil {
+			errs = append(errs, err)
+		}
+		if err := m.unloadMatching("legacy noise-suppression denoised-out sink", "sink_name="+denoisedOut); err != nil {
+			errs = append(errs, err)
+		}
 	} else {
-		fmt.Printf("     - Unloading %d main sink module(s)\n", len(mainModIDs))
-		for _, modID := range mainModIDs {
-			if err := m.client.UnloadModule(modID); err != nil {
-				fmt.Printf("       Warning: failed to unload main sink module %d: %v\n", modID, err)
-			} else {
-				fmt.Printf("       Unloaded main sink module %d\n", modID)
-			}
+		// 1. Unload loopback modules that feed audio into the mic sink.
+		// Match both the monitor source and mic sink to avoid unloading
+		// unrelated modules.
+		monitorSource, _ := m.sinkMonitor(p.VirtualSink)
+		if err := m.unloadMatching("loopback", "source="+monitorSource, "sink="+micSinkName); err != nil {
+			errs = append(errs, err)
+		}
+
+		// 2. Unload the mic sink modules
+		if err := m.unloadMatching("mic sink", "sink_name="+micSinkName); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	// 3. Unload main sink modules
+	if err := m.unloadMatching("main sink", "sink_name="+p.VirtualSink); err != nil {
+		errs = append(errs, err)
+	}
+
+	return joinErrors(errs)
 }
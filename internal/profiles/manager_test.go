@@ -0,0 +1,164 @@
+package profiles
+
+import (
+	"strings"
+	"testing"
+
+	"gemini-audio/internal/pactl"
+)
+
+type recordingBackend struct {
+	fakeBackend
+	sinks      []pactl.Sink
+	loaded     []string // "name args..." for each LoadModule call
+	modules    []pactl.Module
+	serverType pactl.ServerType
+}
+
+func (b *recordingBackend) ServerInfo() (pactl.ServerInfo, error) {
+	return pactl.ServerInfo{Type: b.serverType}, nil
+}
+
+func (b *recordingBackend) ListSinks() ([]pactl.Sink, error) { return b.sinks, nil }
+
+func (b *recordingBackend) ListModules() ([]pactl.Module, error) { return b.modules, nil }
+
+func (b *recordingBackend) LoadModule(name string, args ...string) (int, error) {
+	b.loaded = append(b.loaded, name+" "+strings.Join(args, " "))
+	if name == "module-null-sink" {
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "sink_name=") {
+				sinkName := strings.TrimPrefix(arg, "sink_name=")
+				b.sinks = append(b.sinks, pactl.Sink{Name: sinkName, MonitorSource: sinkName + ".monitor"})
+			}
+		}
+	}
+	return 1, nil
+}
+
+func TestDeployNoiseSuppressionLoadsLadspaSource(t *testing.T) {
+	backend := &recordingBackend{
+		sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+	}
+	mgr := NewManager(backend)
+	p := &Profile{
+		Name:        "meeting",
+		VirtualSink: "virtual-out-meeting",
+		NoiseSuppression: &NoiseSuppression{
+			Plugin:  "librnnoise_ladspa.so",
+			Label:   "noise_suppressor_mono",
+			Control: []string{"50"},
+		},
+	}
+
+	if err := mgr.deployNoiseSuppressionPipeWire(p, "virtual-out-meeting-mic", "Virtual Mic (meeting)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(backend.loaded) != 1 {
+		t.Fatalf("expected exactly one LoadModule call, got %d", len(backend.loaded))
+	}
+	got := backend.loaded[0]
+	for _, want := range []string{
+		"module-ladspa-source",
+		"source_name=virtual-out-meeting-mic",
+		"source_master=virtual-out-meeting.monitor",
+		"plugin=librnnoise_ladspa.so",
+		"label=noise_suppressor_mono",
+		"control=50",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected LoadModule args %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestDeployNoiseSuppressionSkipsIfAlreadyLoaded(t *testing.T) {
+	backend := &recordingBackend{
+		sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+		modules: []pactl.Module{
+			{ID: 9, Name: "module-ladspa-source", Argument: "source_name=virtual-out-meeting-mic plugin=librnnoise_ladspa.so"},
+		},
+	}
+	mgr := NewManager(backend)
+	p := &Profile{
+		Name:             "meeting",
+		VirtualSink:      "virtual-out-meeting",
+		NoiseSuppression: &NoiseSuppression{Plugin: "librnnoise_ladspa.so", Label: "noise_suppressor_mono"},
+	}
+
+	if err := mgr.deployNoiseSuppressionPipeWire(p, "virtual-out-meeting-mic", "Virtual Mic (meeting)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backend.loaded) != 0 {
+		t.Fatalf("expected no LoadModule calls, got %v", backend.loaded)
+	}
+}
+
+func TestDeployNoiseSuppressionLegacyLoadsTwoStageChain(t *testing.T) {
+	backend := &recordingBackend{
+		sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+	}
+	mgr := NewManager(backend)
+	p := &Profile{
+		Name:        "meeting",
+		VirtualSink: "virtual-out-meeting",
+		NoiseSuppression: &NoiseSuppression{
+			Plugin: "librnnoise_ladspa.so",
+			Label:  "noise_suppressor_mono",
+		},
+	}
+
+	if err := mgr.deployNoiseSuppressionLegacy(p, "Virtual Mic (meeting)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(backend.loaded) != 3 {
+		t.Fatalf("expected 3 LoadModule calls (null-sink, ladspa-sink, loopback), got %d: %v", len(backend.loaded), backend.loaded)
+	}
+	if !strings.Contains(backend.loaded[0], "module-null-sink sink_name=meeting_denoised_out") {
+		t.Fatalf("expected denoised-out null-sink first, got %q", backend.loaded[0])
+	}
+	if !strings.Contains(backend.loaded[1], "module-ladspa-sink") || !strings.Contains(backend.loaded[1], "sink_master=meeting_denoised_out") {
+		t.Fatalf("expected LADSPA sink chained to denoised-out, got %q", backend.loaded[1])
+	}
+	if !strings.Contains(backend.loaded[2], "module-loopback") || !strings.Contains(backend.loaded[2], "sink=meeting_raw_in") {
+		t.Fatalf("expected loopback into raw-in, got %q", backend.loaded[2])
+	}
+}
+
+func TestDeployPicksTopologyFromServerType(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		serverType pactl.ServerType
+		wantCalls  int // 1 for PipeWire (single module-ladspa-source), 3 for legacy
+	}{
+		{"pipewire", pactl.ServerPipeWire, 1},
+		{"pulseaudio", pactl.ServerPulseAudio, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := &recordingBackend{
+				serverType: tc.serverType,
+				// The virtual sink already exists so Deploy only has to
+				// pick a noise-suppression topology, not also create it.
+				sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+			}
+			mgr := NewManager(backend)
+			p := &Profile{
+				Name:        "meeting",
+				VirtualSink: "virtual-out-meeting",
+				NoiseSuppression: &NoiseSuppression{
+					Plugin: "librnnoise_ladspa.so",
+					Label:  "noise_suppressor_mono",
+				},
+			}
+
+			if err := mgr.Deploy(p); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(backend.loaded) != tc.wantCalls {
+				t.Fatalf("expected %d LoadModule calls, got %d: %v", tc.wantCalls, len(backend.loaded), backend.loaded)
+			}
+		})
+	}
+}
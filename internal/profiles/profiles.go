@@ -12,10 +12,11 @@ import (
 
 // Profile represents a routing configuration
 type Profile struct {
-	Name         string        `yaml:"name"`
-	Description  string        `yaml:"description"`
-	VirtualSink  string        `yaml:"virtual_sink"`
-	Applications []Application `yaml:"applications"`
+	Name             string            `yaml:"name"`
+	Description      string            `yaml:"description"`
+	VirtualSink      string            `yaml:"virtual_sink"`
+	Applications     []Application     `yaml:"applications"`
+	NoiseSuppression *NoiseSuppression `yaml:"noise_suppression,omitempty"`
 }
 
 // Application within a profile
@@ -24,6 +25,20 @@ type Application struct {
 	Role string `yaml:"role"`
 }
 
+// NoiseSuppression configures a LADSPA filter (e.g. rnnoise) inserted
+// between the virtual sink's monitor and the profile's mic sink, so
+// applications that select the mic get a denoised stream instead of the
+// raw monitor passthrough.
+type NoiseSuppression struct {
+	// Plugin is the LADSPA .so to load, e.g. "librnnoise_ladspa.so".
+	Plugin string `yaml:"plugin"`
+	// Label is the plugin's LADSPA label, e.g. "noise_suppressor_mono".
+	Label string `yaml:"label"`
+	// Control holds the plugin's control-port values in order, e.g. the
+	// rnnoise VAD threshold. Passed through as a comma-separated list.
+	Control []string `yaml:"control"`
+}
+
 // LoadProfile loads a profile from a YAML file
 func LoadProfile(path string) (*Profile, error) {
 	data, err := ioutil.ReadFile(path)
@@ -0,0 +1,234 @@
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gemini-audio/internal/pactl"
+)
+
+// routeDebounce absorbs the burst of sink-input new/change events an app
+// tends to fire while it's still setting up its stream, so we move it once
+// instead of racing its own startup.
+const routeDebounce = 500 * time.Millisecond
+
+// reactorBackoffMax caps how long Router waits between Subscribe retries
+// after the event stream dies (e.g. `pactl subscribe` got killed).
+const reactorBackoffMax = 30 * time.Second
+
+// Router watches the server for new/changed sink inputs and automatically
+// moves ones that match a deployed profile's playback applications onto
+// that profile's virtual sink. It complements Manager.Deploy's one-shot
+// sweep, which only catches whatever is already playing when Deploy runs.
+type Router struct {
+	backend  pactl.Backend
+	profiles []*Profile
+
+	mu     sync.Mutex
+	routed map[int]string // sink input id -> sink it was last routed to
+	timers map[int]*time.Timer
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRouter builds a Router that matches sink inputs against the playback
+// applications of the given profiles.
+func NewRouter(backend pactl.Backend, profiles []*Profile) *Router {
+	return &Router{
+		backend:  backend,
+		profiles: profiles,
+		routed:   make(map[int]string),
+		timers:   make(map[int]*time.Timer),
+	}
+}
+
+// Start begins listening for sink-input events in the background. It
+// restarts the underlying subscription with backoff if it dies.
+func (r *Router) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// Stop ends the subscription and waits for the background goroutine to exit.
+func (r *Router) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+func (r *Router) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	backoff := time.Second
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		events, err := r.backend.Subscribe()
+		if err != nil {
+			fmt.Printf("router: subscribe failed, retrying in %s: %v\n", backoff, err)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = minDuration(backoff*2, reactorBackoffMax)
+			continue
+		}
+
+		started := time.Now()
+		r.consume(ctx, events)
+		if ctx.Err() != nil {
+			return
+		}
+		// A stream that stayed up longer than the cap starts the backoff over.
+		if time.Since(started) > reactorBackoffMax {
+			backoff = time.Second
+		}
+		fmt.Printf("router: event stream ended, resubscribing in %s\n", backoff)
+		if !sleep(ctx, backoff) {
+			return
+		}
+		backoff = minDuration(backoff*2, reactorBackoffMax)
+	}
+}
+
+func (r *Router) consume(ctx context.Context, events <-chan pactl.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Facility != pactl.FacilitySinkInput {
+				continue
+			}
+			if ev.Type == pactl.EventRemove {
+				r.forget(int(ev.Index))
+				continue
+			}
+			r.scheduleRoute(int(ev.Index))
+		}
+	}
+}
+
+// scheduleRoute debounces repeated new/change events for the same sink
+// input id before actually attempting to route it.
+func (r *Router) scheduleRoute(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.timers[id]; ok {
+		t.Reset(routeDebounce)
+		return
+	}
+	r.timers[id] = time.AfterFunc(routeDebounce, func() {
+		r.mu.Lock()
+		delete(r.timers, id)
+		r.mu.Unlock()
+		r.routeByID(id)
+	})
+}
+
+func (r *Router) forget(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.routed, id)
+	if t, ok := r.timers[id]; ok {
+		t.Stop()
+		delete(r.timers, id)
+	}
+}
+
+func (r *Router) routeByID(id int) {
+	inputs, err := r.backend.ListSinkInputs()
+	if err != nil {
+		fmt.Printf("router: failed to list sink inputs: %v\n", err)
+		return
+	}
+	for _, input := range inputs {
+		if input.ID == id {
+			r.OnSinkInput(input)
+			return
+		}
+	}
+}
+
+// OnSinkInput matches a single sink input against the deployed profiles and
+// moves it if it isn't already routed there. Exposed directly so it can be
+// exercised in tests without going through Subscribe.
+func (r *Router) OnSinkInput(input pactl.SinkInput) {
+	sink, ok := r.match(input)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	already := r.routed[input.ID] == sink
+	r.mu.Unlock()
+	if already {
+		return
+	}
+
+	if err := r.backend.MoveSinkInput(input.ID, sink); err != nil {
+		fmt.Printf("router: failed to move sink input #%d to %s: %v\n", input.ID, sink, err)
+		return
+	}
+
+	r.mu.Lock()
+	r.routed[input.ID] = sink
+	r.mu.Unlock()
+	fmt.Printf("router: moved sink input #%d (%s) to %s\n", input.ID, input.ApplicationName, sink)
+}
+
+func (r *Router) match(input pactl.SinkInput) (string, bool) {
+	for _, p := range r.profiles {
+		for _, app := range p.Applications {
+			if app.Role != "playback" {
+				continue
+			}
+			if matchesApplication(input, app.Name) {
+				return p.VirtualSink, true
+			}
+		}
+	}
+	return "", false
+}
+
+func matchesApplication(input pactl.SinkInput, name string) bool {
+	name = strings.ToLower(name)
+	if name == "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(input.ApplicationName), name) {
+		return true
+	}
+	if input.ProcessBinary != "" && strings.Contains(strings.ToLower(input.ProcessBinary), name) {
+		return true
+	}
+	return false
+}
+
+// sleep waits for d, returning false if ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,87 @@
+package profiles
+
+import (
+	"testing"
+
+	"gemini-audio/internal/pactl"
+)
+
+type fakeBackend struct {
+	moved map[int]string
+}
+
+func (f *fakeBackend) ServerInfo() (pactl.ServerInfo, error) {
+	return pactl.ServerInfo{Type: pactl.ServerPipeWire, ServerName: "PulseAudio (on PipeWire 1.0.5)"}, nil
+}
+func (f *fakeBackend) ListSinks() ([]pactl.Sink, error)           { return nil, nil }
+func (f *fakeBackend) ListModules() ([]pactl.Module, error)       { return nil, nil }
+func (f *fakeBackend) ListSinkInputs() ([]pactl.SinkInput, error) { return nil, nil }
+func (f *fakeBackend) LoadModule(string, ...string) (int, error)  { return 0, nil }
+func (f *fakeBackend) UnloadModule(int) error                     { return nil }
+func (f *fakeBackend) Subscribe() (<-chan pactl.Event, error)     { return nil, nil }
+func (f *fakeBackend) MoveSinkInput(id int, sink string) error {
+	if f.moved == nil {
+		f.moved = make(map[int]string)
+	}
+	f.moved[id] = sink
+	return nil
+}
+
+func testProfiles() []*Profile {
+	return []*Profile{
+		{
+			Name:        "meeting",
+			VirtualSink: "virtual-out-meeting",
+			Applications: []Application{
+				{Name: "firefox", Role: "playback"},
+			},
+		},
+	}
+}
+
+func TestRouterOnSinkInputMatchesByApplicationName(t *testing.T) {
+	fb := &fakeBackend{}
+	r := NewRouter(fb, testProfiles())
+
+	r.OnSinkInput(pactl.SinkInput{ID: 5, ApplicationName: "Firefox"})
+
+	if fb.moved[5] != "virtual-out-meeting" {
+		t.Fatalf("expected sink input 5 routed to virtual-out-meeting, got %q", fb.moved[5])
+	}
+}
+
+func TestRouterOnSinkInputMatchesByProcessBinary(t *testing.T) {
+	fb := &fakeBackend{}
+	r := NewRouter(fb, testProfiles())
+
+	r.OnSinkInput(pactl.SinkInput{ID: 6, ApplicationName: "Web Content", ProcessBinary: "firefox"})
+
+	if fb.moved[6] != "virtual-out-meeting" {
+		t.Fatalf("expected sink input 6 routed to virtual-out-meeting, got %q", fb.moved[6])
+	}
+}
+
+func TestRouterOnSinkInputSkipsUnmatched(t *testing.T) {
+	fb := &fakeBackend{}
+	r := NewRouter(fb, testProfiles())
+
+	r.OnSinkInput(pactl.SinkInput{ID: 7, ApplicationName: "Spotify"})
+
+	if _, ok := fb.moved[7]; ok {
+		t.Fatalf("expected unmatched sink input not to be moved")
+	}
+}
+
+func TestRouterOnSinkInputSkipsAlreadyRouted(t *testing.T) {
+	fb := &fakeBackend{}
+	r := NewRouter(fb, testProfiles())
+
+	r.OnSinkInput(pactl.SinkInput{ID: 5, ApplicationName: "Firefox"})
+	fb.moved[5] = "" // would show a second move if OnSinkInput re-moved it
+
+	r.OnSinkInput(pactl.SinkInput{ID: 5, ApplicationName: "Firefox"})
+
+	if fb.moved[5] != "" {
+		t.Fatalf("expected no re-route for an already-routed sink input")
+	}
+}
@@ -0,0 +1,143 @@
+package profiles
+
+import (
+	"fmt"
+	"strings"
+
+	"gemini-audio/internal/pactl"
+)
+
+// ProfileState describes how closely a profile's actual module topology on
+// the server matches what Deploy would create.
+type ProfileState int
+
+const (
+	// Unloaded means none of the profile's modules are present.
+	Unloaded ProfileState = iota
+	// Loaded means every expected module is present.
+	Loaded
+	// Inconsistent means some but not all expected modules are present,
+	// e.g. the daemon was killed mid-deploy or mid-reset.
+	Inconsistent
+)
+
+func (s ProfileState) String() string {
+	switch s {
+	case Loaded:
+		return "loaded"
+	case Inconsistent:
+		return "inconsistent"
+	default:
+		return "unloaded"
+	}
+}
+
+// Status checks the server for each module a deployed profile expects and
+// reports whether the profile is fully Loaded, fully Unloaded, or
+// Inconsistent (partially loaded, e.g. from a crash mid-Deploy/Reset).
+func (m *Manager) Status(p *Profile) (ProfileState, error) {
+	mainExists, err := m.sinkExists(p.VirtualSink)
+	if err != nil {
+		return Unloaded, fmt.Errorf("could not check for sink '%s': %w", p.VirtualSink, err)
+	}
+
+	if p.NoiseSuppression != nil {
+		return m.noiseSuppressionStatus(p, mainExists)
+	}
+
+	micSinkName := p.VirtualSink + "-mic"
+	micExists, err := m.sinkExists(micSinkName)
+	if err != nil {
+		return Unloaded, fmt.Errorf("could not check for mic sink '%s': %w", micSinkName, err)
+	}
+
+	monitorSource, _ := m.sinkMonitor(p.VirtualSink)
+	loopbackIDs, _ := m.findModulesMatching("source="+monitorSource, "sink="+micSinkName)
+	loopbackExists := len(loopbackIDs) > 0
+
+	switch {
+	case !mainExists && !micExists && !loopbackExists:
+		return Unloaded, nil
+	case mainExists && micExists && loopbackExists:
+		return Loaded, nil
+	default:
+		return Inconsistent, nil
+	}
+}
+
+// MicConsumers reports how many streams are currently reading from a
+// profile's virtual mic, via the server's NUsed counter on the module that
+// owns it (the mic null-sink, or the LADSPA source in front of it for a
+// PipeWire noise-suppression profile). It returns -1 if that can't be
+// determined: the mic module isn't loaded, or the backend doesn't report
+// NUsed (PactlBackend always reports -1).
+func (m *Manager) MicConsumers(p *Profile) (int, error) {
+	micSinkName := p.VirtualSink + "-mic"
+	modules, err := m.backend.ListModules()
+	if err != nil {
+		return -1, err
+	}
+	for _, mod := range modules {
+		if strings.Contains(mod.Argument, "sink_name="+micSinkName) || strings.Contains(mod.Argument, "source_name="+micSinkName) {
+			return mod.NUsed, nil
+		}
+	}
+	return -1, nil
+}
+
+func (m *Manager) noiseSuppressionStatus(p *Profile, mainExists bool) (ProfileState, error) {
+	serverType, err := m.detectServerType()
+	if err != nil {
+		return Unloaded, err
+	}
+
+	if serverType == pactl.ServerPipeWire {
+		micSinkName := p.VirtualSink + "-mic"
+		ids, _ := m.findModulesMatching("source_name="+micSinkName, "plugin="+p.NoiseSuppression.Plugin)
+		chainExists := len(ids) > 0
+
+		switch {
+		case !mainExists && !chainExists:
+			return Unloaded, nil
+		case mainExists && chainExists:
+			return Loaded, nil
+		default:
+			return Inconsistent, nil
+		}
+	}
+
+	denoisedOut := p.Name + "_denoised_out"
+	rawIn := p.Name + "_raw_in"
+	denoisedExists := hasMatch(m, "sink_name="+denoisedOut)
+	rawInExists := hasMatch(m, "sink_name="+rawIn)
+	loopExists := hasMatch(m, "sink="+rawIn)
+	allExist := denoisedExists && rawInExists && loopExists
+	noneExist := !denoisedExists && !rawInExists && !loopExists
+
+	switch {
+	case !mainExists && noneExist:
+		return Unloaded, nil
+	case mainExists && allExist:
+		return Loaded, nil
+	default:
+		return Inconsistent, nil
+	}
+}
+
+func hasMatch(m *Manager, substr string) bool {
+	ids, _ := m.findModulesMatching(substr)
+	return len(ids) > 0
+}
+
+// Reconcile brings an Inconsistent (or Unloaded) profile to Loaded by
+// loading only the pieces that are actually missing. It shares the same
+// existence checks Deploy uses for each piece, so calling it on an already
+// Loaded profile is a no-op; unlike Deploy it does not also sweep for and
+// route already-running applications, since that's the reactive Router's
+// job once the topology is in place. A noise-suppression chain is checked
+// as one piece: the legacy chain counts as present once its LADSPA sink is
+// loaded, and otherwise all three of its modules are loaded, so a partly
+// loaded legacy chain is not completed module by module.
+func (m *Manager) Reconcile(p *Profile) error {
+	return m.ensureTopology(p)
+}
@@ -0,0 +1,123 @@
+package profiles
+
+import (
+	"testing"
+
+	"gemini-audio/internal/pactl"
+)
+
+func plainProfile() *Profile {
+	return &Profile{
+		Name:        "meeting",
+		VirtualSink: "virtual-out-meeting",
+	}
+}
+
+func TestStatusUnloadedWhenNothingExists(t *testing.T) {
+	backend := &recordingBackend{}
+	mgr := NewManager(backend)
+
+	state, err := mgr.Status(plainProfile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != Unloaded {
+		t.Fatalf("expected Unloaded, got %s", state)
+	}
+}
+
+func TestStatusLoadedWhenFullyDeployed(t *testing.T) {
+	backend := &recordingBackend{}
+	mgr := NewManager(backend)
+	p := plainProfile()
+
+	if err := mgr.Deploy(p); err != nil {
+		t.Fatalf("unexpected error deploying: %v", err)
+	}
+	// The fake backend's LoadModule adds a sink for each module-null-sink
+	// but records no modules, so set the sinks and the loopback module a
+	// real server would now report before asking for Status.
+	backend.sinks = []pactl.Sink{
+		{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"},
+		{Name: "virtual-out-meeting-mic"},
+	}
+	backend.modules = []pactl.Module{
+		{ID: 1, Name: "module-loopback", Argument: "source=virtual-out-meeting.monitor sink=virtual-out-meeting-mic"},
+	}
+
+	state, err := mgr.Status(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != Loaded {
+		t.Fatalf("expected Loaded, got %s", state)
+	}
+}
+
+func TestStatusInconsistentWhenPartiallyLoaded(t *testing.T) {
+	backend := &recordingBackend{
+		sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+	}
+	mgr := NewManager(backend)
+
+	state, err := mgr.Status(plainProfile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != Inconsistent {
+		t.Fatalf("expected Inconsistent, got %s", state)
+	}
+}
+
+func TestStatusNoiseSuppressionPipeWire(t *testing.T) {
+	p := &Profile{
+		Name:        "meeting",
+		VirtualSink: "virtual-out-meeting",
+		NoiseSuppression: &NoiseSuppression{
+			Plugin: "librnnoise_ladspa.so",
+			Label:  "noise_suppressor_mono",
+		},
+	}
+
+	backend := &recordingBackend{serverType: pactl.ServerPipeWire}
+	mgr := NewManager(backend)
+	state, err := mgr.Status(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != Unloaded {
+		t.Fatalf("expected Unloaded, got %s", state)
+	}
+
+	backend.sinks = []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}}
+	backend.modules = []pactl.Module{
+		{ID: 2, Name: "module-ladspa-source", Argument: "source_name=virtual-out-meeting-mic plugin=librnnoise_ladspa.so"},
+	}
+	state, err = mgr.Status(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != Loaded {
+		t.Fatalf("expected Loaded, got %s", state)
+	}
+}
+
+func TestReconcileLoadsOnlyMissingPieces(t *testing.T) {
+	backend := &recordingBackend{
+		sinks: []pactl.Sink{{Name: "virtual-out-meeting", MonitorSource: "virtual-out-meeting.monitor"}},
+	}
+	mgr := NewManager(backend)
+	p := plainProfile()
+	p.Applications = []Application{{Name: "firefox", Role: "playback"}}
+
+	if err := mgr.Reconcile(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The main sink already existed, so Reconcile should only have created
+	// the mic sink and loopback. The fake reports no sink inputs, so this
+	// does not show whether an application sweep ran.
+	if len(backend.loaded) != 2 {
+		t.Fatalf("expected 2 LoadModule calls (mic sink, loopback), got %d: %v", len(backend.loaded), backend.loaded)
+	}
+}
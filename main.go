@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -8,10 +9,26 @@ import (
 	"sync"
 	"syscall"
 
+	"gemini-audio/internal/journal"
 	"gemini-audio/internal/pactl"
+	"gemini-audio/internal/pactl/native"
 	"gemini-audio/internal/profiles"
 )
 
+// newBackend picks the best available way to talk to the server: the native
+// protocol if the socket is reachable, falling back to shelling out via
+// pactl otherwise (e.g. remote sessions, or sandboxes without a running
+// PulseAudio/PipeWire server).
+func newBackend(client *pactl.Client) pactl.Backend {
+	if client.DryRun {
+		return pactl.NewPactlBackend(client)
+	}
+	if nb, err := native.Dial(); err == nil {
+		return nb
+	}
+	return pactl.NewPactlBackend(client)
+}
+
 func usage() {
 	fmt.Println("Usage: gemini-audio [options] [command]")
 	fmt.Println()
@@ -22,7 +39,7 @@ func usage() {
 	fmt.Println("CLI Commands:")
 	fmt.Println("  help                              Show this help message")
 	fmt.Println("  list-profiles                     List available profiles")
-	fmt.Println("  status                            Show current audio sinks")
+	fmt.Println("  status                            Show each profile's loaded/unloaded state")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --dry-run                         Print commands without executing them")
@@ -53,12 +70,41 @@ func handleCommand(cmd string, args []string, client *pactl.Client, profilesDir
 	case "help":
 		usage()
 	case "status":
-		out, err := client.Pactl("list", "short", "sinks")
+		profileList, err := profiles.ListProfiles(profilesDir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(2)
+			fmt.Fprintf(os.Stderr, "error reading profiles directory: %v\n", err)
+			os.Exit(4)
+		}
+		if len(profileList) == 0 {
+			fmt.Println("No profiles found.")
+			return
+		}
+
+		backend := newBackend(client)
+		manager := profiles.NewManager(backend)
+
+		fmt.Printf("%-20s %s\n", "PROFILE", "STATE")
+		for _, name := range profileList {
+			profilePath := fmt.Sprintf("%s/%s", profilesDir, name)
+			profile, err := profiles.LoadProfile(profilePath)
+			if err != nil {
+				fmt.Printf("%-20s error loading profile: %v\n", name, err)
+				continue
+			}
+			state, err := manager.Status(profile)
+			if err != nil {
+				fmt.Printf("%-20s error checking status: %v\n", profile.Name, err)
+				continue
+			}
+			line := fmt.Sprintf("%-20s %s", profile.Name, state)
+			if state == profiles.Inconsistent {
+				line += "  ⚠ run as daemon to reconcile"
+			}
+			if n, err := manager.MicConsumers(profile); err == nil && n > 0 {
+				line += fmt.Sprintf("  🎙 mic in use (%d stream(s))", n)
+			}
+			fmt.Println(line)
 		}
-		fmt.Println(string(out))
 	case "list-profiles":
 		profileList, err := profiles.ListProfiles(profilesDir)
 		if err != nil {
@@ -83,7 +129,14 @@ func handleCommand(cmd string, args []string, client *pactl.Client, profilesDir
 
 func runDaemon(dry bool, profilesDir string) {
 	client := pactl.New(dry)
-	manager := profiles.NewManager(client)
+	backend := newBackend(client)
+	manager := profiles.NewManager(backend)
+
+	// Reap any pactl subscribe children (see PactlBackend.Subscribe) that
+	// outlive the code reading their events, so they don't linger as
+	// zombies across router restarts.
+	stopReaper := pactl.ReapChildProcs()
+	defer stopReaper()
 
 	// Track deployed profiles for cleanup
 	deployedProfiles := make([]*profiles.Profile, 0)
@@ -105,7 +158,8 @@ func runDaemon(dry bool, profilesDir string) {
 	fmt.Printf("📁 Profiles directory: %s\n", profilesDir)
 	fmt.Printf("📋 Found %d profile(s)\n", len(profileList))
 
-	// Deploy all profiles
+	var toDeploy []*profiles.Profile
+	byName := make(map[string]*profiles.Profile)
 	for _, profileName := range profileList {
 		profilePath := fmt.Sprintf("%s/%s", profilesDir, profileName)
 		profile, err := profiles.LoadProfile(profilePath)
@@ -113,20 +167,38 @@ func runDaemon(dry bool, profilesDir string) {
 			fmt.Fprintf(os.Stderr, "error loading profile %s: %v\n", profileName, err)
 			continue
 		}
+		toDeploy = append(toDeploy, profile)
+		byName[profile.Name] = profile
+	}
+
+	reclaimStaleModules(manager, backend, byName)
 
+	// Deploy all profiles
+	for _, profile := range toDeploy {
 		fmt.Printf("\n📝 Deploying profile: %s\n", profile.Name)
 		fmt.Printf("   Description: %s\n", profile.Description)
 
 		if err := manager.Deploy(profile); err != nil {
 			fmt.Fprintf(os.Stderr, "error deploying profile %s: %v\n", profile.Name, err)
+			continue
+		}
+		fmt.Printf("   ✅ Profile deployed successfully\n")
+		mu.Lock()
+		deployedProfiles = append(deployedProfiles, profile)
+		mu.Unlock()
+
+		if ids, err := manager.ModuleIDsFor(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to record journal entry for %s: %v\n", profile.Name, err)
 		} else {
-			fmt.Printf("   ✅ Profile deployed successfully\n")
-			mu.Lock()
-			deployedProfiles = append(deployedProfiles, profile)
-			mu.Unlock()
+			saveJournalEntry(profile.Name, ids)
 		}
 	}
 
+	// Start the reactive router so apps that start after the initial deploy
+	// sweep still get routed to the right profile's virtual sink.
+	router := profiles.NewRouter(backend, deployedProfiles)
+	router.Start(context.Background())
+
 	fmt.Printf("\n✨ All profiles deployed. Daemon running...\n")
 	fmt.Println("Press Ctrl+C to shutdown and cleanup.")
 
@@ -137,6 +209,9 @@ func runDaemon(dry bool, profilesDir string) {
 	// Wait for signal
 	sig := <-sigChan
 	fmt.Printf("\n\n🛑 Received signal: %v\n", sig)
+
+	router.Stop()
+
 	fmt.Println("🧹 Cleaning up deployed profiles...")
 
 	// Reset profiles in reverse order
@@ -146,12 +221,100 @@ func runDaemon(dry bool, profilesDir string) {
 		fmt.Printf("\n🔄 Resetting profile: %s\n", profile.Name)
 		if err := manager.Reset(profile); err != nil {
 			fmt.Fprintf(os.Stderr, "error resetting profile %s: %v\n", profile.Name, err)
-		} else {
-			fmt.Printf("   ✅ Profile reset successfully\n")
+			continue
 		}
+		fmt.Printf("   ✅ Profile reset successfully\n")
+		removeJournalEntry(profile.Name)
 	}
 	mu.Unlock()
 
 	fmt.Println("\n👋 Daemon shutdown complete.")
 	os.Exit(0)
 }
+
+// reclaimStaleModules recovers from a daemon that didn't get to shut down
+// gracefully (SIGKILL, crash, reboot). For a profile whose YAML is still
+// around, it reconciles rather than tearing down: a Loaded profile is left
+// alone and an Inconsistent one is patched in place with Reconcile, so a
+// crash-restart doesn't interrupt whatever was already routed through it.
+// Only a profile whose YAML is gone gets its modules unloaded module-by-module,
+// since there's nothing left to reconcile against.
+func reclaimStaleModules(manager *profiles.Manager, backend pactl.Backend, byName map[string]*profiles.Profile) {
+	j, err := journal.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to read deployment journal: %v\n", err)
+		return
+	}
+	if len(j.Entries) == 0 {
+		return
+	}
+
+	fmt.Println("🧹 Found a stale deployment journal from a previous run, reconciling before redeploying...")
+	for _, entry := range j.Entries {
+		if profile, ok := byName[entry.Profile]; ok {
+			state, err := manager.Status(profile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "     warning: failed to check status of stale profile %s, leaving it in the journal: %v\n", entry.Profile, err)
+				continue
+			}
+			switch state {
+			case profiles.Loaded:
+				fmt.Printf("   - Stale profile '%s' is already fully loaded, leaving it in place\n", entry.Profile)
+			case profiles.Inconsistent:
+				fmt.Printf("   - Reconciling partially-loaded stale profile: %s\n", entry.Profile)
+				if err := manager.Reconcile(profile); err != nil {
+					fmt.Fprintf(os.Stderr, "     warning: failed to reconcile stale profile %s, leaving it in the journal: %v\n", entry.Profile, err)
+					continue
+				}
+				if ids, err := manager.ModuleIDsFor(profile); err == nil {
+					j.Set(entry.Profile, ids)
+				}
+			default: // Unloaded: nothing left to reconcile against
+				j.Remove(entry.Profile)
+			}
+			continue
+		}
+
+		fmt.Printf("   - Profile '%s' no longer exists, unloading its %d module(s) directly\n", entry.Profile, len(entry.ModuleIDs))
+		var remaining []int
+		for _, id := range entry.ModuleIDs {
+			if err := backend.UnloadModule(id); err != nil {
+				fmt.Fprintf(os.Stderr, "     warning: failed to unload stale module %d: %v\n", id, err)
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == 0 {
+			j.Remove(entry.Profile)
+		} else {
+			j.Set(entry.Profile, remaining)
+		}
+	}
+
+	if err := j.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save deployment journal: %v\n", err)
+	}
+}
+
+func saveJournalEntry(profileName string, moduleIDs []int) {
+	j, err := journal.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to read deployment journal: %v\n", err)
+		return
+	}
+	j.Set(profileName, moduleIDs)
+	if err := j.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save deployment journal: %v\n", err)
+	}
+}
+
+func removeJournalEntry(profileName string) {
+	j, err := journal.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to read deployment journal: %v\n", err)
+		return
+	}
+	j.Remove(profileName)
+	if err := j.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save deployment journal: %v\n", err)
+	}
+}